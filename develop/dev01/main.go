@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/beevik/ntp"
@@ -20,27 +27,178 @@ import (
 Программа должна проходить проверки go vet и golint.
 */
 
+// defaultServers — серверы, которые опрашиваются, если список не передан в аргументах.
+var defaultServers = []string{
+	"0.beevik-ntp.pool.ntp.org",
+	"1.beevik-ntp.pool.ntp.org",
+	"2.beevik-ntp.pool.ntp.org",
+	"3.beevik-ntp.pool.ntp.org",
+}
+
+// Коды выхода программы, по одному на каждый вид ошибки.
+const (
+	exitOK              = 0 // Время получено и выведено
+	exitFailure         = 1 // Не удалось вывести результат
+	exitUsage           = 2 // Неверные аргументы командной строки
+	exitUnreachable     = 3 // Ни один сервер не ответил
+	exitInvalidResponse = 4 // Ответы получены, но ни один не прошёл проверку
+	exitDrift           = 5 // Смещение часов превысило порог в режиме наблюдения
+)
+
 // main является точкой входа в программу.
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run разбирает аргументы, получает время и печатает результат.
+// Возвращает код выхода; сообщения об ошибках пишутся в stderr.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "serve" {
+		return runServe(args[1:], stderr)
+	}
+
+	// Серверы передаются позиционными аргументами, режим вывода и таймаут — флагами.
+	fs := flag.NewFlagSet("dev01", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	timeout := fs.Duration("timeout", 5*time.Second, "таймаут запроса к одному серверу")
+	reportMode := fs.Bool("report", false, "вывести полный отчёт о выбранном ответе")
+	format := fs.String("format", "text", "формат отчёта: text или json")
+	monitorMode := fs.Bool("monitor", false, "непрерывно следить за смещением часов")
+	interval := fs.Duration("interval", time.Minute, "период опроса в режиме наблюдения")
+	history := fs.Int("history", 10, "размер скользящего окна смещений")
+	threshold := fs.Duration("threshold", 100*time.Millisecond, "допустимое смещение часов")
+	webhook := fs.String("webhook", "", "URL для уведомлений о превышении порога (без него программа завершается)")
+	listen := fs.String("listen", "localhost:9123", "адрес HTTP-сервера с метриками /metrics (пусто — не запускать)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "error: unknown report format %q: must be text or json\n", *format)
+		return exitUsage
+	}
+	if *interval <= 0 {
+		fmt.Fprintf(stderr, "error: poll interval must be positive, got %v\n", *interval)
+		return exitUsage
+	}
+
+	servers := fs.Args()
+	if len(servers) == 0 {
+		servers = defaultServers
+	}
+
+	if *monitorMode {
+		// В режиме наблюдения работаем до сигнала завершения или до тревоги.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		m := newMonitor(monitorConfig{
+			servers:   servers,
+			opt:       ntp.QueryOptions{Timeout: *timeout},
+			interval:  *interval,
+			history:   *history,
+			threshold: *threshold,
+			webhook:   *webhook,
+		}, stderr)
+		if *listen != "" {
+			if err := serveMetrics(ctx, *listen, m); err != nil {
+				fmt.Fprintln(stderr, "error: metrics server:", err)
+				return exitFailure
+			}
+		}
+		if err := m.run(ctx); err != nil {
+			fmt.Fprintln(stderr, "error:", err)
+			return exitDrift
+		}
+		return exitOK
+	}
+
 	// Вызов функции get_time для получения текущего времени по NTP.
-	t, err := get_time()
+	t, best, err := get_time(servers, ntp.QueryOptions{Timeout: *timeout})
 	if err != nil {
-		// Если произошла ошибка, завершаем выполнение программы с кодом ошибки 1.
-		os.Exit(1)
+		// Если произошла ошибка, печатаем её и выбираем код выхода по её виду.
+		fmt.Fprintln(stderr, "error:", err)
+		if errors.Is(err, errNoResponses) {
+			return exitUnreachable
+		}
+		return exitInvalidResponse
 	}
-	// Если ошибок нет, выводим текущее время.
-	fmt.Println("Time:", t)
+
+	if !*reportMode {
+		// Если ошибок нет, выводим текущее время и сервер, ответ которого был выбран.
+		fmt.Fprintln(stdout, "Time:", t)
+		fmt.Fprintf(stdout, "Server: %s (%s)\n", best.server, best.reason)
+		return exitOK
+	}
+
+	// В режиме отчёта выводим все поля выбранного ответа.
+	r := newReport(t, best)
+	if *format == "json" {
+		err = r.writeJSON(stdout)
+	} else {
+		err = r.writeText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error: writing report:", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runServe запускает SNTP-сервер и обслуживает запросы до сигнала завершения.
+func runServe(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dev01 serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", "localhost:1123", "UDP-адрес, на котором принимаются запросы")
+	offset := fs.Duration("offset", 0, "сдвиг отдаваемого времени относительно локальных часов")
+	skew := fs.Float64("skew", 0, "уход отдаваемых часов в микросекундах за секунду (ppm)")
+	stratum := fs.Int("stratum", 1, "уровень сервера (0 — kiss of death, 16 — не синхронизирован)")
+	leap := fs.Int("leap", 0, "индикатор високосной секунды (3 — часы не синхронизированы)")
+	refID := fs.String("refid", "LOCL", "идентификатор источника времени, до 4 символов")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	logger := log.New(stderr, "", log.LstdFlags)
+	srv, err := newSNTPServer(*listen, skewedClock(*offset, *skew), *stratum, *leap, *refID, logger)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitUsage
+	}
+
+	// Закрываем сокет по сигналу, чтобы serve вернул управление.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.close()
+	}()
+
+	logger.Printf("serving SNTP on %s (offset %v, skew %gppm, stratum %d)", srv.addr(), *offset, *skew, *stratum)
+	if err := srv.serve(); err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	return exitOK
 }
 
-// get_time получает текущее время с NTP-сервера.
-// Возвращает текущее время и ошибку, если таковая имеется.
-func get_time() (time.Time, error) {
-	// Запрашиваем текущее время у NTP-сервера.
-	t, err := ntp.Time("0.beevik-ntp.pool.ntp.org")
+// get_time опрашивает все серверы из списка и получает текущее время по лучшему ответу.
+// Возвращает текущее время, выбранный ответ и ошибку, если таковая имеется.
+func get_time(servers []string, opt ntp.QueryOptions) (time.Time, selection, error) {
+	// Запрашиваем время у всех серверов одновременно.
+	samples := queryAll(servers, opt)
+
+	// Выбираем ответ, которому можно доверять больше остальных.
+	best, err := selectBest(samples)
 	if err != nil {
-		// Если произошла ошибка при запросе времени, возвращаем пустое время и ошибку.
-		return time.Time{}, err
+		// Если ни один сервер не дал корректного ответа, возвращаем пустое время и ошибку.
+		return time.Time{}, selection{}, err
 	}
-	// Возвращаем текущее время и ошибку (если она есть).
-	return t, err
+	// Корректируем локальное время на смещение, вычисленное по выбранному ответу.
+	return time.Now().Add(best.response.ClockOffset), best, nil
 }
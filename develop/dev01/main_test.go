@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// startFakeServer запускает локальный SNTP-сервер, часы которого сдвинуты на offset.
+func startFakeServer(t *testing.T, offset time.Duration, stratum int) string {
+	t.Helper()
+
+	srv, err := newSNTPServer("127.0.0.1:0", skewedClock(offset, 0), stratum, 0, "TEST", log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.close() })
+	go srv.serve()
+
+	return srv.addr()
+}
+
+var testOptions = ntp.QueryOptions{Timeout: time.Second}
+
+func TestGetTimeOutvotesLyingServer(t *testing.T) {
+	honest1 := startFakeServer(t, 0, 2)
+	liar := startFakeServer(t, time.Hour, 1)
+	honest2 := startFakeServer(t, 0, 2)
+
+	now, best, err := get_time([]string{honest1, liar, honest2}, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if best.server == liar {
+		t.Errorf("selected lying server %s", liar)
+	}
+	if !strings.Contains(best.reason, "2 из 3") {
+		t.Errorf("unexpected reason %q", best.reason)
+	}
+	if d := time.Since(now); d > time.Second || d < -time.Second {
+		t.Errorf("time is off by %v", d)
+	}
+}
+
+func TestGetTimeSkipsFailedServers(t *testing.T) {
+	good := startFakeServer(t, 0, 2)
+	invalid := startFakeServer(t, 0, 16) // Stratum 16 не проходит Validate.
+
+	_, best, err := get_time([]string{"127.0.0.1:1", invalid, good}, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if best.server != good {
+		t.Errorf("selected %s, expected %s", best.server, good)
+	}
+}
+
+func TestGetTimeNoValidResponses(t *testing.T) {
+	invalid := startFakeServer(t, 0, 0) // Stratum 0 — kiss of death.
+
+	_, _, err := get_time([]string{invalid}, testOptions)
+	if err == nil {
+		t.Fatal("expected an error, but got nil")
+	}
+	if !errors.Is(err, errNoValidSamples) {
+		t.Errorf("error %q is not errNoValidSamples", err)
+	}
+	if !strings.Contains(err.Error(), invalid) {
+		t.Errorf("error %q does not mention server %s", err, invalid)
+	}
+}
+
+func TestMarzullo(t *testing.T) {
+	samples := []sample{
+		{response: &ntp.Response{ClockOffset: 10 * time.Millisecond, RootDistance: 5 * time.Millisecond}},
+		{response: &ntp.Response{ClockOffset: 12 * time.Millisecond, RootDistance: 4 * time.Millisecond}},
+		{response: &ntp.Response{ClockOffset: 90 * time.Millisecond, RootDistance: 1 * time.Millisecond}},
+	}
+
+	lo, hi, count := marzullo(samples)
+	if count != 2 || lo != 8*time.Millisecond || hi != 15*time.Millisecond {
+		t.Errorf("marzullo result is [%v, %v] x%d, expected [8ms, 15ms] x2", lo, hi, count)
+	}
+
+	best, err := selectBest(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if best.response != samples[1].response {
+		t.Errorf("selected offset %v, expected %v", best.response.ClockOffset, samples[1].response.ClockOffset)
+	}
+}
+
+func TestRunReportJSON(t *testing.T) {
+	server := startFakeServer(t, 0, 2)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-report", "-format", "json", "-timeout", "1s", server}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code is %d, expected %d: %s", code, exitOK, stderr.String())
+	}
+
+	var r map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"clock_offset_seconds", "rtt_seconds", "stratum", "reference_id", "root_dispersion_seconds", "leap_indicator"} {
+		if _, ok := r[field]; !ok {
+			t.Errorf("report has no field %s: %s", field, stdout.String())
+		}
+	}
+	if r["server"] != server || r["reference_id"] != "84.69.83.84" || r["leap_indicator"] != "no warning" {
+		t.Errorf("unexpected report %s", stdout.String())
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	invalid := startFakeServer(t, 0, 16)
+
+	cases := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"bad flag", []string{"-no-such-flag"}, exitUsage},
+		{"bad format", []string{"-format", "xml", "127.0.0.1:1"}, exitUsage},
+		{"unreachable", []string{"-timeout", "1s", "127.0.0.1:1"}, exitUnreachable},
+		{"invalid response", []string{"-timeout", "1s", invalid}, exitInvalidResponse},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(c.args, &stdout, &stderr); code != c.code {
+				t.Errorf("exit code is %d, expected %d", code, c.code)
+			}
+			if stderr.Len() == 0 {
+				t.Error("expected an error message in stderr")
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("unexpected output %q", stdout.String())
+			}
+		})
+	}
+}
+
+func TestServerSkewedClock(t *testing.T) {
+	server := startFakeServer(t, 90*time.Second, 1)
+
+	resp, err := ntp.QueryWithOptions(server, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := resp.ClockOffset - 90*time.Second; d > 10*time.Millisecond || d < -10*time.Millisecond {
+		t.Errorf("clock offset is %v, expected 1m30s", resp.ClockOffset)
+	}
+	if resp.Stratum != 1 || resp.ReferenceString() != ".TEST." || resp.Version != 4 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestSkewedClock(t *testing.T) {
+	clock := skewedClock(0, 1e6) // Часы идут вдвое быстрее.
+	first := clock()
+	time.Sleep(50 * time.Millisecond)
+	if d := clock().Sub(first); d < 100*time.Millisecond {
+		t.Errorf("skewed clock advanced %v, expected at least 100ms", d)
+	}
+}
+
+func TestRunServeInvalidArguments(t *testing.T) {
+	for _, args := range [][]string{{"-stratum", "17"}, {"-leap", "4"}, {"-refid", "TOOLONG"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(append([]string{"serve", "-listen", "127.0.0.1:0"}, args...), &stdout, &stderr); code != exitUsage {
+			t.Errorf("serve %v exit code is %d, expected %d", args, code, exitUsage)
+		}
+	}
+}
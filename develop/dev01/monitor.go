@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// errDrift возвращается монитором, если смещение часов превысило порог, а webhook не задан.
+var errDrift = errors.New("clock offset exceeds threshold")
+
+// monitorConfig — настройки режима непрерывного наблюдения за смещением часов.
+type monitorConfig struct {
+	servers   []string         // Опрашиваемые серверы
+	opt       ntp.QueryOptions // Параметры запросов к серверам
+	interval  time.Duration    // Период опроса
+	history   int              // Размер скользящего окна смещений
+	threshold time.Duration    // Допустимое смещение часов
+	webhook   string           // URL для уведомлений; если пуст, монитор завершается при тревоге
+}
+
+// monitor периодически опрашивает NTP-серверы, хранит историю смещений
+// и отдаёт последние измерения в формате Prometheus.
+type monitor struct {
+	cfg    monitorConfig
+	logger *log.Logger
+	client *http.Client
+
+	mu       sync.Mutex
+	offsets  []time.Duration // Скользящее окно последних смещений, от старых к новым
+	server   string          // Сервер, выбранный при последнем успешном опросе
+	last     *ntp.Response   // Ответ, выбранный при последнем успешном опросе
+	polls    uint64          // Общее число опросов
+	failures uint64          // Число опросов, не давших корректного ответа
+	alerting bool            // Превышен ли порог сейчас
+}
+
+// alert — тело уведомления, отправляемого на webhook при входе в тревогу и выходе из неё.
+type alert struct {
+	Alerting     bool      `json:"alerting"`
+	Server       string    `json:"server"`
+	ClockOffset  float64   `json:"clock_offset_seconds"`
+	MedianOffset float64   `json:"median_offset_seconds"`
+	Threshold    float64   `json:"threshold_seconds"`
+	Time         time.Time `json:"time"`
+}
+
+// newMonitor создаёт монитор, который пишет журнал в w.
+func newMonitor(cfg monitorConfig, w io.Writer) *monitor {
+	if cfg.history < 1 {
+		cfg.history = 1
+	}
+	return &monitor{
+		cfg:     cfg,
+		logger:  log.New(w, "", log.LstdFlags),
+		client:  &http.Client{Timeout: 5 * time.Second},
+		offsets: make([]time.Duration, 0, cfg.history),
+	}
+}
+
+// run опрашивает серверы каждые cfg.interval до отмены ctx.
+// Возвращает errDrift, если порог превышен и webhook не задан.
+func (m *monitor) run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.poll(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll выполняет один опрос, обновляет историю и проверяет порог.
+// Ошибки опроса и отправки уведомлений только журналируются, чтобы монитор продолжал работу.
+func (m *monitor) poll(ctx context.Context) error {
+	_, best, err := get_time(m.cfg.servers, m.cfg.opt)
+
+	m.mu.Lock()
+	m.polls++
+	if err != nil {
+		m.failures++
+		m.mu.Unlock()
+		m.logger.Println("error:", err)
+		return nil
+	}
+
+	m.server, m.last = best.server, best.response
+	if len(m.offsets) == m.cfg.history {
+		m.offsets = append(m.offsets[:0], m.offsets[1:]...) // Выталкиваем самое старое смещение.
+	}
+	m.offsets = append(m.offsets, best.response.ClockOffset)
+
+	// Порог сравнивается с медианой окна, чтобы одиночный выброс не поднимал тревогу.
+	median := medianOffset(m.offsets)
+	alerting := median > m.cfg.threshold || median < -m.cfg.threshold
+	changed := alerting != m.alerting
+	m.alerting = alerting
+	m.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	if alerting {
+		m.logger.Printf("alert: median clock offset %v exceeds threshold %v (server %s)", median, m.cfg.threshold, best.server)
+	} else {
+		m.logger.Printf("recovered: median clock offset %v is within threshold %v (server %s)", median, m.cfg.threshold, best.server)
+	}
+
+	if m.cfg.webhook == "" {
+		if alerting {
+			return fmt.Errorf("%w: median offset %v, threshold %v", errDrift, median, m.cfg.threshold)
+		}
+		return nil
+	}
+
+	err = m.notify(ctx, alert{
+		Alerting:     alerting,
+		Server:       best.server,
+		ClockOffset:  best.response.ClockOffset.Seconds(),
+		MedianOffset: median.Seconds(),
+		Threshold:    m.cfg.threshold.Seconds(),
+		Time:         time.Now(),
+	})
+	if err != nil {
+		m.logger.Println("error: webhook:", err)
+	}
+	return nil
+}
+
+// notify отправляет уведомление POST-запросом с JSON-телом на cfg.webhook.
+func (m *monitor) notify(ctx context.Context, a alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// ServeHTTP отдаёт метрики в текстовом формате Prometheus.
+func (m *monitor) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	writeMetric(w, "ntp_polls_total", "counter", "Total number of NTP polls.", "", float64(m.polls))
+	writeMetric(w, "ntp_poll_failures_total", "counter", "Number of NTP polls without a valid response.", "", float64(m.failures))
+	if m.last == nil {
+		return
+	}
+
+	labels := fmt.Sprintf(`{server="%s"}`, labelEscaper.Replace(m.server))
+	writeMetric(w, "ntp_clock_offset_seconds", "gauge", "Clock offset of the local clock relative to the selected NTP server.", labels, m.last.ClockOffset.Seconds())
+	writeMetric(w, "ntp_clock_offset_median_seconds", "gauge", "Median clock offset over the rolling history window.", "", medianOffset(m.offsets).Seconds())
+	writeMetric(w, "ntp_rtt_seconds", "gauge", "Round-trip delay to the selected NTP server.", labels, m.last.RTT.Seconds())
+	writeMetric(w, "ntp_stratum", "gauge", "Stratum of the selected NTP server.", labels, float64(m.last.Stratum))
+	writeMetric(w, "ntp_offset_threshold_seconds", "gauge", "Configured clock offset alert threshold.", "", m.cfg.threshold.Seconds())
+
+	alerting := 0.0
+	if m.alerting {
+		alerting = 1
+	}
+	writeMetric(w, "ntp_drift_alert", "gauge", "Whether the median clock offset exceeds the threshold.", "", alerting)
+}
+
+// labelEscaper экранирует значение метки по правилам текстового формата Prometheus.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetric печатает одну метрику вместе с её описанием и типом.
+func writeMetric(w io.Writer, name, kind, help, labels string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s%s %g\n", name, help, name, kind, name, labels, value)
+}
+
+// medianOffset возвращает медиану смещений, не изменяя исходный слайс.
+func medianOffset(offsets []time.Duration) time.Duration {
+	if len(offsets) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// serveMetrics запускает HTTP-сервер с обработчиком /metrics и останавливает его при отмене ctx.
+// Порт занимается сразу, чтобы ошибка адреса была видна до начала опроса.
+func serveMetrics(ctx context.Context, addr string, m *monitor) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.logger.Println("error: metrics server:", err)
+		}
+	}()
+
+	m.logger.Println("serving metrics on", ln.Addr())
+	return nil
+}
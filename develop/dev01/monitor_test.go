@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMonitor(server string, webhook string) *monitor {
+	return newMonitor(monitorConfig{
+		servers:   []string{server},
+		opt:       testOptions,
+		interval:  10 * time.Millisecond,
+		history:   3,
+		threshold: time.Second,
+		webhook:   webhook,
+	}, io.Discard)
+}
+
+func TestMonitorMetrics(t *testing.T) {
+	server := startFakeServer(t, 0, 2)
+	m := newTestMonitor(server, "")
+
+	if err := m.poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	expected := []string{
+		"# TYPE ntp_clock_offset_seconds gauge\nntp_clock_offset_seconds{server=\"" + server + "\"} ",
+		"# TYPE ntp_rtt_seconds gauge\nntp_rtt_seconds{server=\"" + server + "\"} ",
+		"ntp_stratum{server=\"" + server + "\"} 2\n",
+		"ntp_polls_total 1\n",
+		"ntp_poll_failures_total 0\n",
+		"ntp_drift_alert 0\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Errorf("metrics do not contain %q:\n%s", e, body)
+		}
+	}
+}
+
+func TestMonitorWebhook(t *testing.T) {
+	alerts := make(chan alert, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Error(err)
+		}
+		alerts <- a
+	}))
+	defer hook.Close()
+
+	server := startFakeServer(t, time.Hour, 2)
+	m := newTestMonitor(server, hook.URL)
+
+	if err := m.poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case a := <-alerts:
+		if !a.Alerting || a.Server != server || a.ClockOffset < 3599 {
+			t.Errorf("unexpected alert %+v", a)
+		}
+	default:
+		t.Fatal("webhook was not called")
+	}
+
+	// Повторный опрос не меняет состояние, поэтому уведомление не отправляется.
+	if err := m.poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 0 {
+		t.Error("webhook was called twice for the same alert")
+	}
+}
+
+func TestMonitorExitsOnDrift(t *testing.T) {
+	server := startFakeServer(t, -time.Hour, 2)
+	m := newTestMonitor(server, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := m.run(ctx); !errors.Is(err, errDrift) {
+		t.Errorf("run returned %v, expected errDrift", err)
+	}
+}
+
+func TestMedianOffset(t *testing.T) {
+	cases := []struct {
+		offsets  []time.Duration
+		expected time.Duration
+	}{
+		{nil, 0},
+		{[]time.Duration{5}, 5},
+		{[]time.Duration{9, 1, 5}, 5},
+		{[]time.Duration{1, 100, 3, 5}, 4},
+	}
+
+	for _, c := range cases {
+		if result := medianOffset(c.offsets); result != c.expected {
+			t.Errorf("median of %v is %v, expected %v", c.offsets, result, c.expected)
+		}
+	}
+}
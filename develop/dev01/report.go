@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// report — полный отчёт о выбранном ответе NTP-сервера.
+// Длительности в JSON передаются в секундах, чтобы их было удобно читать системам мониторинга.
+type report struct {
+	Server         string    `json:"server"`
+	Reason         string    `json:"reason"`
+	Time           time.Time `json:"time"`
+	ClockOffset    float64   `json:"clock_offset_seconds"`
+	RTT            float64   `json:"rtt_seconds"`
+	Stratum        uint8     `json:"stratum"`
+	ReferenceID    string    `json:"reference_id"`
+	RootDelay      float64   `json:"root_delay_seconds"`
+	RootDispersion float64   `json:"root_dispersion_seconds"`
+	RootDistance   float64   `json:"root_distance_seconds"`
+	Leap           string    `json:"leap_indicator"`
+
+	response *ntp.Response
+}
+
+// newReport собирает отчёт по выбранному ответу и вычисленному по нему времени.
+func newReport(t time.Time, best selection) report {
+	r := best.response
+	return report{
+		Server:         best.server,
+		Reason:         best.reason,
+		Time:           t,
+		ClockOffset:    r.ClockOffset.Seconds(),
+		RTT:            r.RTT.Seconds(),
+		Stratum:        r.Stratum,
+		ReferenceID:    r.ReferenceString(),
+		RootDelay:      r.RootDelay.Seconds(),
+		RootDispersion: r.RootDispersion.Seconds(),
+		RootDistance:   r.RootDistance.Seconds(),
+		Leap:           leapString(r.Leap),
+		response:       r,
+	}
+}
+
+// writeText печатает отчёт в виде выровненной таблицы «поле: значение».
+func (r report) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "Server:\t%s\n", r.Server)
+	fmt.Fprintf(tw, "Selected:\t%s\n", r.Reason)
+	fmt.Fprintf(tw, "Time:\t%s\n", r.Time.Format(time.RFC3339Nano))
+	fmt.Fprintf(tw, "Clock offset:\t%v\n", r.response.ClockOffset)
+	fmt.Fprintf(tw, "Round-trip delay:\t%v\n", r.response.RTT)
+	fmt.Fprintf(tw, "Stratum:\t%d\n", r.Stratum)
+	fmt.Fprintf(tw, "Reference ID:\t%s\n", r.ReferenceID)
+	fmt.Fprintf(tw, "Root delay:\t%v\n", r.response.RootDelay)
+	fmt.Fprintf(tw, "Root dispersion:\t%v\n", r.response.RootDispersion)
+	fmt.Fprintf(tw, "Root distance:\t%v\n", r.response.RootDistance)
+	fmt.Fprintf(tw, "Leap indicator:\t%s\n", r.Leap)
+	return tw.Flush()
+}
+
+// writeJSON печатает отчёт одним JSON-объектом.
+func (r report) writeJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// leapString возвращает название индикатора високосной секунды.
+func leapString(li ntp.LeapIndicator) string {
+	switch li {
+	case ntp.LeapNoWarning:
+		return "no warning"
+	case ntp.LeapAddSecond:
+		return "add second"
+	case ntp.LeapDelSecond:
+		return "delete second"
+	case ntp.LeapNotInSync:
+		return "not in sync"
+	}
+	return fmt.Sprintf("unknown (%d)", li)
+}
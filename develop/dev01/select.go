@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+var (
+	// errNoResponses возвращается, если ни один сервер не ответил на запрос.
+	errNoResponses = errors.New("no NTP server responded")
+	// errNoValidSamples возвращается, если ответы получены, но ни один не прошёл проверку.
+	errNoValidSamples = errors.New("no valid NTP responses")
+)
+
+// sample — результат опроса одного NTP-сервера.
+type sample struct {
+	server   string        // Адрес опрошенного сервера
+	response *ntp.Response // Ответ сервера (nil, если запрос не удался)
+	err      error         // Ошибка запроса или проверки ответа
+}
+
+// selection — выбранный ответ вместе с объяснением, почему выбран именно он.
+type selection struct {
+	sample
+	reason string
+}
+
+// queryAll опрашивает все серверы одновременно и возвращает ответы в порядке списка серверов.
+// Ответы, не прошедшие Response.Validate, помечаются ошибкой проверки.
+func queryAll(servers []string, opt ntp.QueryOptions) []sample {
+	samples := make([]sample, len(servers))
+
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			resp, err := ntp.QueryWithOptions(server, opt)
+			if err == nil {
+				err = resp.Validate() // Отбрасываем KoD, несинхронизированные и устаревшие ответы.
+			}
+			samples[i] = sample{server: server, response: resp, err: err}
+		}(i, server)
+	}
+	wg.Wait()
+
+	return samples
+}
+
+// selectBest выбирает лучший ответ среди корректных.
+// Каждый ответ задаёт интервал [offset-rootDistance, offset+rootDistance], в котором лежит
+// истинное время. Алгоритм Марзулло находит пересечение, с которым согласно наибольшее
+// число серверов; если согласно большинство, выбирается сервер с наименьшей корневой
+// дистанцией среди согласных, иначе — с наименьшей корневой дистанцией среди всех.
+func selectBest(samples []sample) (selection, error) {
+	valid := make([]sample, 0, len(samples))
+	errs := []error{errNoResponses}
+	for _, s := range samples {
+		if s.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.server, s.err))
+			if s.response != nil {
+				errs[0] = errNoValidSamples // Хотя бы один сервер ответил.
+			}
+			continue
+		}
+		valid = append(valid, s)
+	}
+
+	switch len(valid) {
+	case 0:
+		return selection{}, errors.Join(errs...)
+	case 1:
+		return selection{sample: valid[0], reason: "единственный корректный ответ"}, nil
+	}
+
+	lo, hi, agreed := marzullo(valid)
+	if agreed > len(valid)/2 {
+		// Ищем среди серверов, чей интервал содержит пересечение, самый точный.
+		best := lowestRootDistance(valid, func(s sample) bool {
+			l, h := interval(s.response)
+			return l <= lo && h >= hi
+		})
+		return selection{
+			sample: best,
+			reason: fmt.Sprintf("пересечение Марзулло: согласны %d из %d серверов, наименьшая корневая дистанция %v",
+				agreed, len(valid), best.response.RootDistance),
+		}, nil
+	}
+
+	// Большинства нет — доверяем серверу с наименьшей оценкой ошибки.
+	best := lowestRootDistance(valid, func(sample) bool { return true })
+	return selection{
+		sample: best,
+		reason: fmt.Sprintf("нет согласного большинства (%d из %d), наименьшая корневая дистанция %v",
+			agreed, len(valid), best.response.RootDistance),
+	}, nil
+}
+
+// interval возвращает интервал корректности ответа относительно локальных часов.
+func interval(r *ntp.Response) (time.Duration, time.Duration) {
+	return r.ClockOffset - r.RootDistance, r.ClockOffset + r.RootDistance
+}
+
+// marzullo возвращает границы пересечения, которое покрывает наибольшее число интервалов,
+// и количество этих интервалов.
+func marzullo(samples []sample) (lo, hi time.Duration, count int) {
+	type edge struct {
+		at   time.Duration
+		kind int // -1 — начало интервала, +1 — конец
+	}
+
+	edges := make([]edge, 0, 2*len(samples))
+	for _, s := range samples {
+		l, h := interval(s.response)
+		edges = append(edges, edge{at: l, kind: -1}, edge{at: h, kind: +1})
+	}
+	// Начала идут раньше концов в той же точке, чтобы касающиеся интервалы считались пересекающимися.
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at != edges[j].at {
+			return edges[i].at < edges[j].at
+		}
+		return edges[i].kind < edges[j].kind
+	})
+
+	current := 0
+	for i, e := range edges {
+		current -= e.kind
+		if current > count {
+			// Счётчик растёт только на начале интервала, поэтому следующая граница существует.
+			count, lo, hi = current, e.at, edges[i+1].at
+		}
+	}
+	return lo, hi, count
+}
+
+// lowestRootDistance возвращает ответ с наименьшей корневой дистанцией среди подходящих под keep.
+func lowestRootDistance(samples []sample, keep func(sample) bool) sample {
+	var best sample
+	for _, s := range samples {
+		if !keep(s) {
+			continue
+		}
+		if best.response == nil || s.response.RootDistance < best.response.RootDistance {
+			best = s
+		}
+	}
+	return best
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+// Поля пакета NTP, используемые сервером (RFC 4330).
+const (
+	packetSize   = 48
+	modeClient   = 3
+	modeServer   = 4
+	maxStratum   = 16
+	precisionExp = -20 // Точность часов ≈ 1µs
+)
+
+// ntpEpoch — начало отсчёта времени NTP.
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sntpServer отвечает на запросы SNTPv4 временем часов clock.
+type sntpServer struct {
+	conn    net.PacketConn
+	clock   func() time.Time // Источник времени, который отдаёт сервер
+	stratum uint8            // Уровень сервера; 0 — kiss of death
+	refID   [4]byte          // Идентификатор источника времени
+	leap    uint8            // Индикатор високосной секунды
+	logger  *log.Logger
+}
+
+// newSNTPServer проверяет параметры и занимает UDP-адрес addr.
+func newSNTPServer(addr string, clock func() time.Time, stratum, leap int, refID string, logger *log.Logger) (*sntpServer, error) {
+	if stratum < 0 || stratum > maxStratum {
+		return nil, fmt.Errorf("stratum must be in range 0..%d, got %d", maxStratum, stratum)
+	}
+	if leap < 0 || leap > 3 {
+		return nil, fmt.Errorf("leap indicator must be in range 0..3, got %d", leap)
+	}
+	if len(refID) > 4 {
+		return nil, fmt.Errorf("reference ID must be at most 4 characters, got %q", refID)
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sntpServer{
+		conn:    conn,
+		clock:   clock,
+		stratum: uint8(stratum),
+		leap:    uint8(leap),
+		logger:  logger,
+	}
+	copy(s.refID[:], refID)
+	return s, nil
+}
+
+// addr возвращает адрес, на котором сервер принимает запросы.
+func (s *sntpServer) addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// close останавливает сервер.
+func (s *sntpServer) close() error {
+	return s.conn.Close()
+}
+
+// serve принимает запросы до закрытия сервера. Некорректные пакеты молча отбрасываются.
+func (s *sntpServer) serve() error {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		recv := s.clock() // Время получения запроса фиксируем как можно раньше.
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		resp, ok := s.respond(buf[:n], recv)
+		if !ok {
+			continue
+		}
+		if _, err := s.conn.WriteTo(resp, addr); err != nil {
+			s.logger.Println("error: reply to", addr, err)
+		}
+	}
+}
+
+// respond формирует ответ на запрос req, полученный в момент recv.
+// Возвращает false, если req не является клиентским запросом.
+func (s *sntpServer) respond(req []byte, recv time.Time) ([]byte, bool) {
+	if len(req) < packetSize {
+		return nil, false
+	}
+	version := req[0] >> 3 & 0x7
+	if req[0]&0x7 != modeClient || version < 1 || version > 4 {
+		return nil, false
+	}
+
+	resp := make([]byte, packetSize)
+	resp[0] = s.leap<<6 | version<<3 | modeServer // Версия повторяет версию запроса.
+	resp[1] = s.stratum
+	resp[2] = req[2] // Интервал опроса повторяет интервал клиента.
+	resp[3] = byte(precisionExp & 0xff)
+	binary.BigEndian.PutUint32(resp[4:], 0)                        // Root delay: сервер сам является источником
+	binary.BigEndian.PutUint32(resp[8:], 1<<16/1000)               // Root dispersion ≈ 1ms
+	copy(resp[12:16], s.refID[:])                                  // Reference ID
+	binary.BigEndian.PutUint64(resp[16:], ntpTimestamp(recv))      // Reference time
+	copy(resp[24:32], req[40:48])                                  // Origin time = transmit time клиента
+	binary.BigEndian.PutUint64(resp[32:], ntpTimestamp(recv))      // Receive time
+	binary.BigEndian.PutUint64(resp[40:], ntpTimestamp(s.clock())) // Transmit time
+	return resp, true
+}
+
+// ntpTimestamp переводит время в 64-битный формат NTP (секунды и доли секунды с 1900 года).
+func ntpTimestamp(t time.Time) uint64 {
+	d := t.Sub(ntpEpoch)
+	sec := uint64(d / time.Second)
+	frac := uint64(d%time.Second) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+// skewedClock возвращает локальные часы, сдвинутые на offset и уходящие вперёд
+// на skewPPM микросекунд за каждую секунду работы сервера.
+func skewedClock(offset time.Duration, skewPPM float64) func() time.Time {
+	start := time.Now()
+	return func() time.Time {
+		now := time.Now()
+		drift := time.Duration(float64(now.Sub(start)) * skewPPM / 1e6)
+		return now.Add(offset + drift)
+	}
+}
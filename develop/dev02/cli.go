@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Коды выхода командной строки.
+const (
+	exitOK      = 0 // Все входные данные обработаны
+	exitInvalid = 1 // Входные данные некорректны
+	exitUsage   = 2 // Неверные аргументы командной строки
+	exitIO      = 3 // Ошибка чтения или записи
+)
+
+// errInvalidUTF8 — упаковываемые данные не являются корректной строкой в UTF-8.
+var errInvalidUTF8 = errors.New("invalid UTF-8")
+
+const usage = `usage: dev02 unpack|pack [-lines] [-max-output N] [-max-repeat N] [file ...]
+
+Распаковывает или упаковывает файлы (или stdin, если файлы не заданы или имя файла "-").
+`
+
+// inputError привязывает ошибку к месту во входных данных.
+type inputError struct {
+	name string // Имя файла
+	line int    // Номер строки (с единицы)
+	err  error
+}
+
+func (e *inputError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.name, e.line, e.err)
+}
+
+func (e *inputError) Unwrap() error {
+	return e.err
+}
+
+// command преобразует одну строку или весь поток целиком.
+type command interface {
+	line(w io.Writer, s string) error
+	whole(w io.Writer, r io.Reader) error
+}
+
+// unpackCommand распаковывает данные с помощью Unpacker.
+type unpackCommand struct {
+	u Unpacker
+}
+
+func (c unpackCommand) line(w io.Writer, s string) error {
+	_, err := c.u.Unpack(w, strings.NewReader(s))
+	return err
+}
+
+func (c unpackCommand) whole(w io.Writer, r io.Reader) error {
+	_, err := c.u.Unpack(w, r)
+	return err
+}
+
+// packCommand упаковывает данные с помощью StringPack.
+type packCommand struct{}
+
+func (packCommand) line(w io.Writer, s string) error {
+	if !utf8.ValidString(s) {
+		return errInvalidUTF8
+	}
+	_, err := io.WriteString(w, StringPack(s))
+	return err
+}
+
+func (c packCommand) whole(w io.Writer, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if !utf8.Valid(data) {
+		// Номер строки с первым некорректным байтом.
+		valid := data
+		for len(valid) > 0 {
+			c, size := utf8.DecodeRune(valid)
+			if c == utf8.RuneError && size == 1 {
+				break
+			}
+			valid = valid[size:]
+		}
+		line := 1 + bytes.Count(data[:len(data)-len(valid)], []byte("\n"))
+		return &inputError{line: line, err: errInvalidUTF8}
+	}
+	return c.line(w, string(data))
+}
+
+// run выполняет подкоманду unpack или pack и возвращает код выхода.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("dev02 "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	lines := fs.Bool("lines", false, "обрабатывать каждую строку отдельно")
+
+	var cmd command
+	switch args[0] {
+	case "unpack":
+		var c unpackCommand
+		fs.Int64Var(&c.u.MaxOutput, "max-output", 64<<20, "максимальный размер результата в байтах (в режиме -lines — для каждой строки), 0 — без ограничения")
+		fs.IntVar(&c.u.MaxRepeat, "max-repeat", 0, "максимальное значение счётчика повторов, 0 — без ограничения")
+		cmd = &c // Флаги заполняют c при разборе.
+	case "pack":
+		cmd = packCommand{}
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s", args[0], usage)
+		return exitUsage
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	out := bufio.NewWriter(stdout)
+	err := processFiles(cmd, files, *lines, stdin, out)
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err == nil {
+		return exitOK
+	}
+
+	fmt.Fprintln(stderr, "error:", err)
+	var unpackErr *UnpackError
+	if errors.As(err, &unpackErr) || errors.Is(err, errInvalidUTF8) {
+		return exitInvalid
+	}
+	return exitIO
+}
+
+// processFiles обрабатывает файлы по порядку и останавливается на первой ошибке.
+func processFiles(cmd command, files []string, lines bool, stdin io.Reader, w io.Writer) error {
+	for _, name := range files {
+		r, closeFile := stdin, func() error { return nil }
+		if name == "-" {
+			name = "stdin"
+		} else {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			r, closeFile = f, f.Close
+		}
+
+		var err error
+		if lines {
+			err = processLines(cmd, name, r, w)
+		} else {
+			err = processWhole(cmd, name, r, w)
+		}
+		closeFile()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processLines обрабатывает каждую строку отдельно; перевод строки не входит в данные.
+// Результат строки выводится, только если она обработана без ошибок.
+func processLines(cmd command, name string, r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	var result bytes.Buffer
+	for n := 1; ; n++ {
+		s, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if s == "" && err == io.EOF {
+			return nil
+		}
+
+		result.Reset()
+		if lineErr := cmd.line(&result, strings.TrimSuffix(s, "\n")); lineErr != nil {
+			return &inputError{name: name, line: n, err: lineErr}
+		}
+		result.WriteByte('\n')
+		if _, werr := result.WriteTo(w); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// processWhole обрабатывает весь поток как одну строку.
+func processWhole(cmd command, name string, r io.Reader, w io.Writer) error {
+	err := cmd.whole(w, r)
+
+	var unpackErr *UnpackError
+	var inErr *inputError
+	switch {
+	case errors.As(err, &unpackErr):
+		return &inputError{name: name, line: unpackErr.Line, err: err}
+	case errors.As(err, &inErr):
+		inErr.name = name
+		return inErr
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLines(t *testing.T) {
+	cases := []struct {
+		args     []string
+		input    string
+		expected string
+	}{
+		{[]string{"unpack", "-lines"}, "a4bc2\nqwe\\45\n", "aaaabcc\nqwe44444\n"},
+		{[]string{"unpack", "-lines"}, "abc", "abc\n"},
+		{[]string{"pack", "-lines"}, "aaaabcc\nqwe44444\n", "a4bc2\nqwe\\45\n"},
+		{[]string{"pack"}, "aaaa\n11\n", "a4\n\\12\n"},
+		{[]string{"unpack", "-"}, "a4\n\\12\n", "aaaa\n11\n"},
+	}
+
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		code := run(c.args, strings.NewReader(c.input), &stdout, &stderr)
+		if code != exitOK {
+			t.Errorf("%v exit code is %d, expected %d: %s", c.args, code, exitOK, stderr.String())
+		}
+		if stdout.String() != c.expected {
+			t.Errorf("%v output is %q, expected %q", c.args, stdout.String(), c.expected)
+		}
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	os.WriteFile(first, []byte("a3\n"), 0o644)
+	os.WriteFile(second, []byte("b2\nc\\\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"unpack", "-lines", first, second}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitInvalid {
+		t.Errorf("exit code is %d, expected %d", code, exitInvalid)
+	}
+	if stdout.String() != "aaa\nbb\n" {
+		t.Errorf("output is %q, expected %q", stdout.String(), "aaa\nbb\n")
+	}
+	if expected := second + ":2:"; !strings.Contains(stderr.String(), expected) {
+		t.Errorf("error %q does not contain %q", stderr.String(), expected)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	cases := []struct {
+		args  []string
+		input string
+		code  int
+		err   string
+	}{
+		{nil, "", exitUsage, "usage"},
+		{[]string{"repack"}, "", exitUsage, "unknown command"},
+		{[]string{"unpack", "-max-repeat", "5"}, "ok\na6", exitInvalid, "stdin:2:"},
+		{[]string{"pack"}, "ok\n\xff", exitInvalid, "stdin:2: invalid UTF-8"},
+		{[]string{"pack", "-lines"}, "ok\nok\n\xff\n", exitInvalid, "stdin:3: invalid UTF-8"},
+		{[]string{"unpack"}, "a3\n\\x\n", exitInvalid, "stdin:2: invalid string at position 1: only digits"},
+		{[]string{"unpack"}, "a3\nbc\n\\", exitInvalid, "stdin:3: invalid string at position 1: dangling escape"},
+		{[]string{"unpack", "-max-repeat", "5"}, "ab\nжc6", exitInvalid, "stdin:2: invalid string at position 3:"},
+		{[]string{"unpack", "/no/such/file"}, "", exitIO, "no such file"},
+	}
+
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		if code := run(c.args, strings.NewReader(c.input), &stdout, &stderr); code != c.code {
+			t.Errorf("%v exit code is %d, expected %d", c.args, code, c.code)
+		}
+		if !strings.Contains(stderr.String(), c.err) {
+			t.Errorf("%v error %q does not contain %q", c.args, stderr.String(), c.err)
+		}
+	}
+}
@@ -1,10 +1,9 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"os"
 	"strconv"
-	"unicode"
+	"strings"
 )
 
 /*
@@ -25,64 +24,55 @@ import (
 Функция должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
+// defaultUnpacker ограничивает результат StringUnpack, чтобы строка вида "a999999999"
+// не исчерпала память.
+var defaultUnpacker = Unpacker{MaxOutput: 64 << 20}
+
 // StringUnpack функция, которая распаковывает строку.
+// Символ, за которым следует число, повторяется это число раз. Обратная косая черта
+// экранирует цифру или саму себя, чтобы их можно было повторять как обычные символы.
+// Результат ограничен 64 МиБ; для других ограничений и потоковой обработки используйте Unpacker.
 func StringUnpack(str string) (string, error) {
-	s := []rune(str)
-
-	// Проверяем, что строка не пустая и не начинается с цифры.
-	if str != "" && unicode.IsDigit(s[0]) {
-		return "", fmt.Errorf("invalid string: cannot start with digit: %s", string(s[0]))
+	var newStr strings.Builder
+	if _, err := defaultUnpacker.Unpack(&newStr, strings.NewReader(str)); err != nil {
+		return "", err
 	}
+	return newStr.String(), nil
+}
 
-	var (
-		newStr []rune
-		numStr string
-		char   rune
-	)
+// StringPack функция, которая упаковывает строку в формат, понятный StringUnpack.
+// Серии одинаковых символов заменяются символом и длиной серии, а цифры и обратная
+// косая черта экранируются, поэтому StringUnpack(StringPack(s)) == s для любой строки в UTF-8.
+func StringPack(str string) string {
+	s := []rune(str)
 
-	for i, r := range s {
-		// Если символ цифра, добавляем его к numStr.
-		if unicode.IsDigit(r) {
-			if numStr == "" { // Проверяем, чтобы символ не перезаписался на число.
-				char = s[i-1] // Сохраняем предыдущий символ.
-			}
-			numStr += string(s[i]) // Добавляем цифру к numStr.
-			if i == len(str)-1 {   // Если это последний символ строки, распаковываем символы.
-				symbolsUnpack(&newStr, &numStr, char)
-			}
-		} else {
-			// Распаковываем накопленные символы и добавляем текущий символ в результат.
-			symbolsUnpack(&newStr, &numStr, char)
-			newStr = append(newStr, r)
+	var packed strings.Builder
+	for i := 0; i < len(s); {
+		// Находим конец серии одинаковых символов.
+		j := i + 1
+		for j < len(s) && s[j] == s[i] {
+			j++
 		}
-	}
-
-	return string(newStr), nil
-}
 
-// symbolsUnpack функция, которая распаковывает символ.
-func symbolsUnpack(newStr *[]rune, numStr *string, char rune) {
-	if *numStr != "" {
-		num, err := strconv.Atoi(*numStr) // Преобразуем строку в число.
-		if err != nil {
-			fmt.Println("error:", err)
-			newStr = nil
-			return
+		if s[i] == '\\' || isDigit(s[i]) {
+			packed.WriteRune('\\')
 		}
-
-		// Добавляем char num-1 раз, так как один раз он уже был добавлен.
-		for i := 1; i < num; i++ {
-			*newStr = append(*newStr, char)
+		packed.WriteRune(s[i])
+		if n := j - i; n > 1 {
+			packed.WriteString(strconv.Itoa(n))
 		}
-
-		*numStr = "" // Обнуляем numStr после распаковки.
+		i = j
 	}
+
+	return packed.String()
 }
 
+// isDigit сообщает, является ли руна ASCII-цифрой. Цифры других алфавитов считаются обычными символами.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// main является точкой входа в программу.
 func main() {
-	result, err := StringUnpack("abcd")
-	if err != nil {
-		log.Fatal(err)
-	}
-	fmt.Println(result) // "aaaabccddddde"
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -1,6 +1,10 @@
 package main
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"unicode/utf8"
+)
 
 func TestUnpackValidWithNumbersCase(t *testing.T) {
 	input := "a4bc2d5e10"
@@ -57,3 +61,114 @@ func TestInvalidCase(t *testing.T) {
 		t.Errorf("unpack result is %s, expected %s", result, expected)
 	}
 }
+
+func TestUnpackSpecCases(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"a4bc2d5e", "aaaabccddddde"},
+		{"abcd", "abcd"},
+		{"", ""},
+		{`qwe\4\5`, "qwe45"},
+		{`qwe\45`, "qwe44444"},
+		{`qwe\\5`, `qwe\\\\\`},
+		{`\\`, `\`},
+		{`a\1\0`, "a10"},
+		{`\12`, "11"},
+		{"a0b", "b"},
+		{"пр3ивет", "пррривет"},
+		{"٣2", "٣٣"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			result, err := StringUnpack(c.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != c.expected {
+				t.Errorf("unpack result is %s, expected %s", result, c.expected)
+			}
+		})
+	}
+}
+
+func TestUnpackErrorCases(t *testing.T) {
+	cases := []struct {
+		input string
+		err   error
+		pos   int
+	}{
+		{"45", ErrDigitStart, 1},
+		{`qwe\`, ErrDanglingEscape, 4},
+		{`qwe\4\`, ErrDanglingEscape, 6},
+		{`ab\c`, ErrInvalidEscape, 3},
+		{"a99999999999999999999", ErrTooLarge, 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			result, err := StringUnpack(c.input)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("error is %v, expected %v", err, c.err)
+			}
+
+			var unpackErr *UnpackError
+			if !errors.As(err, &unpackErr) {
+				t.Fatalf("error %v is not *UnpackError", err)
+			}
+			if unpackErr.Pos != c.pos {
+				t.Errorf("error position is %d, expected %d", unpackErr.Pos, c.pos)
+			}
+			if result != "" {
+				t.Errorf("unpack result is %s, expected empty string", result)
+			}
+		})
+	}
+}
+
+func TestPackCases(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"abcd", "abcd"},
+		{"aaaabccddddde", "a4bc2d5e"},
+		{"aaaaaaaaaaaa", "a12"},
+		{"qwe45", `qwe\4\5`},
+		{"qwe44444", `qwe\45`},
+		{`qwe\\\\\`, `qwe\\5`},
+		{"пррривет", "пр3ивет"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			if result := StringPack(c.input); result != c.expected {
+				t.Errorf("pack result is %s, expected %s", result, c.expected)
+			}
+		})
+	}
+}
+
+func FuzzPackRoundTrip(f *testing.F) {
+	for _, seed := range []string{"", "abcd", "aaaabccddddde", "qwe45", `qwe\\\`, "1111111111", "пятак", "a\x00\x00b"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			t.Skip()
+		}
+
+		packed := StringPack(input)
+		result, err := StringUnpack(packed)
+		if err != nil {
+			t.Fatalf("unpack of %q (packed from %q) failed: %v", packed, input, err)
+		}
+		if result != input {
+			t.Errorf("round trip of %q through %q gave %q", input, packed, result)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"unicode/utf8"
+)
+
+// Ошибки распаковки. Возвращаются обёрнутыми в *UnpackError с позицией ошибки.
+var (
+	// ErrDigitStart — строка начинается со счётчика, которому не предшествует символ.
+	ErrDigitStart = errors.New("count without preceding character")
+	// ErrDanglingEscape — строка заканчивается обратной косой чертой.
+	ErrDanglingEscape = errors.New("dangling escape at end of string")
+	// ErrInvalidEscape — экранирован символ, отличный от цифры и обратной косой черты.
+	ErrInvalidEscape = errors.New("only digits and backslash can be escaped")
+	// ErrTooLarge — счётчик или размер результата превышают допустимые значения.
+	ErrTooLarge = errors.New("unpacked output is too large")
+)
+
+// UnpackError описывает некорректный фрагмент входной строки.
+type UnpackError struct {
+	Pos  int   // Номер руны от начала потока, с которой начинается ошибка (с единицы)
+	Line int   // Номер строки, на которой находится ошибка (с единицы)
+	Col  int   // Номер руны в строке Line (с единицы); для однострочного ввода равен Pos
+	Err  error // Причина ошибки
+}
+
+func (e *UnpackError) Error() string {
+	return fmt.Sprintf("invalid string at position %d: %v", e.Col, e.Err)
+}
+
+func (e *UnpackError) Unwrap() error {
+	return e.Err
+}
+
+// Unpacker распаковывает поток, не загружая его в память целиком.
+// Нулевое значение готово к работе и не ограничивает результат.
+type Unpacker struct {
+	MaxOutput int64 // Максимальный размер результата в байтах; 0 — без ограничения
+	MaxRepeat int   // Максимальное значение счётчика повторов; 0 — без ограничения
+}
+
+// Unpack читает упакованные данные из src и пишет распакованные в dst.
+// Возвращает число записанных байт. При ошибке в dst остаётся всё, что было распаковано до неё.
+func (u *Unpacker) Unpack(dst io.Writer, src io.Reader) (int64, error) {
+	r := bufio.NewReader(src)
+	w := &countingWriter{w: bufio.NewWriter(dst)}
+
+	err := u.unpack(w, r)
+	if flushErr := w.w.Flush(); err == nil {
+		err = flushErr
+	}
+	return w.n, err
+}
+
+// unpack разбирает поток по одной руне, заглядывая на руну вперёд, чтобы найти конец счётчика.
+func (u *Unpacker) unpack(w *countingWriter, r *bufio.Reader) error {
+	maxRepeat := u.MaxRepeat
+	if maxRepeat <= 0 {
+		maxRepeat = math.MaxInt
+	}
+
+	// at — место последней прочитанной руны; строка меняется на руне после перевода строки.
+	at, newline := position{line: 1}, false
+	next := func() (rune, error) {
+		c, _, err := r.ReadRune()
+		if err == nil {
+			at.pos++
+			at.col++
+			if newline {
+				at.line, at.col = at.line+1, 1
+			}
+			newline = c == '\n'
+		}
+		return c, err
+	}
+	fail := func(at position, err error) error {
+		return &UnpackError{Pos: at.pos, Line: at.line, Col: at.col, Err: err}
+	}
+
+	c, err := next()
+	for err == nil {
+		charPos, char := at, c
+
+		// Читаем очередной символ, с учётом экранирования.
+		switch {
+		case c == '\\':
+			char, err = next()
+			if err == io.EOF {
+				return fail(charPos, ErrDanglingEscape)
+			}
+			if err != nil {
+				return err
+			}
+			if char != '\\' && !isDigit(char) {
+				return fail(charPos, ErrInvalidEscape)
+			}
+		case isDigit(c):
+			// Счётчик может идти только после символа; после счётчика цифры продолжают его.
+			return fail(charPos, ErrDigitStart)
+		}
+
+		// Читаем счётчик повторов, если он есть, проверяя его по мере чтения цифр.
+		count := 1
+		c, err = next()
+		if err == nil && isDigit(c) {
+			countPos := at
+			count = 0
+			for err == nil && isDigit(c) {
+				d := int(c - '0')
+				if count > (maxRepeat-d)/10 || count*10+d > maxRepeat {
+					return fail(countPos, ErrTooLarge)
+				}
+				count = count*10 + d
+				c, err = next()
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		size := int64(utf8.RuneLen(char))
+		if u.MaxOutput > 0 && int64(count) > (u.MaxOutput-w.n)/size {
+			return fail(charPos, ErrTooLarge)
+		}
+		for i := 0; i < count; i++ {
+			if _, werr := w.WriteRune(char); werr != nil {
+				return werr
+			}
+		}
+	}
+
+	if err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// position — место руны во входных данных; все номера с единицы.
+type position struct {
+	pos  int // Номер руны от начала потока
+	line int // Номер строки
+	col  int // Номер руны в строке
+}
+
+// countingWriter считает байты, записанные в буферизованный поток.
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (cw *countingWriter) WriteRune(r rune) (int, error) {
+	n, err := cw.w.WriteRune(r)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestUnpackerStream(t *testing.T) {
+	input := `a4bc2d5e10qwe\4\5\\3пр3ивет`
+	expected, err := StringUnpack(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	var u Unpacker
+	n, err := u.Unpack(&out, iotest.OneByteReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("unpack result is %s, expected %s", out.String(), expected)
+	}
+	if n != int64(out.Len()) {
+		t.Errorf("unpack reported %d bytes, wrote %d", n, out.Len())
+	}
+}
+
+func TestUnpackerLimits(t *testing.T) {
+	cases := []struct {
+		name     string
+		unpacker Unpacker
+		input    string
+		pos      int
+	}{
+		{"repeat limit", Unpacker{MaxRepeat: 100}, "ab101", 3},
+		{"repeat limit single digit", Unpacker{MaxRepeat: 5}, "a6", 2},
+		{"repeat limit exact", Unpacker{MaxRepeat: 100}, "ab100c1000", 7},
+		{"output limit", Unpacker{MaxOutput: 10}, "a5b6", 3},
+		{"output limit in bytes", Unpacker{MaxOutput: 5}, "ж3", 1},
+		{"overflow without limits", Unpacker{}, "a999999999999999999999", 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out bytes.Buffer
+			_, err := c.unpacker.Unpack(&out, strings.NewReader(c.input))
+			if !errors.Is(err, ErrTooLarge) {
+				t.Fatalf("error is %v, expected %v", err, ErrTooLarge)
+			}
+
+			var unpackErr *UnpackError
+			if !errors.As(err, &unpackErr) {
+				t.Fatalf("error %v is not *UnpackError", err)
+			}
+			if unpackErr.Pos != c.pos {
+				t.Errorf("error position is %d, expected %d", unpackErr.Pos, c.pos)
+			}
+		})
+	}
+}
+
+func TestUnpackerReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+
+	var out bytes.Buffer
+	var u Unpacker
+	_, err := u.Unpack(&out, iotest.ErrReader(readErr))
+	if !errors.Is(err, readErr) {
+		t.Errorf("error is %v, expected %v", err, readErr)
+	}
+}
+
+func TestUnpackErrorPosition(t *testing.T) {
+	var out bytes.Buffer
+	var u Unpacker
+	_, err := u.Unpack(&out, strings.NewReader("ab\nж\\x"))
+
+	var unpackErr *UnpackError
+	if !errors.As(err, &unpackErr) {
+		t.Fatalf("error %v is not *UnpackError", err)
+	}
+	// Позиция в потоке считается с начала ввода, столбец — с начала строки.
+	if unpackErr.Pos != 5 || unpackErr.Line != 2 || unpackErr.Col != 2 {
+		t.Errorf("error at pos %d, line %d, col %d; expected pos 5, line 2, col 2", unpackErr.Pos, unpackErr.Line, unpackErr.Col)
+	}
+	if expected := "invalid string at position 2: " + ErrInvalidEscape.Error(); err.Error() != expected {
+		t.Errorf("error is %q, expected %q", err.Error(), expected)
+	}
+}
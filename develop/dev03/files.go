@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"dev03/linesort"
+)
+
+// inputs — открытые входные файлы сортировки.
+type inputs struct {
+	readers []io.Reader
+	files   []*os.File
+	temps   []string // Временные копии входных файлов, совпадающих с выходным
+}
+
+// openInputs открывает входные файлы по порядку; имя "-" означает stdin.
+// Входной файл, совпадающий с output, сначала копируется во временный файл в tempDir,
+// чтобы запись результата не испортила ещё не прочитанные данные.
+func openInputs(names []string, output, tempDir string) (*inputs, error) {
+	var outInfo os.FileInfo
+	if output != "" {
+		info, err := os.Stat(output)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		outInfo = info
+	}
+
+	in := &inputs{}
+	for _, name := range names {
+		if name == "-" {
+			in.readers = append(in.readers, os.Stdin)
+			continue
+		}
+
+		file, err := os.Open(name)
+		if err != nil {
+			in.close()
+			return nil, err
+		}
+		if outInfo != nil {
+			info, err := file.Stat()
+			if err == nil && os.SameFile(info, outInfo) {
+				file, err = in.copyToTemp(file, tempDir)
+			}
+			if err != nil {
+				file.Close()
+				in.close()
+				return nil, err
+			}
+		}
+		in.files = append(in.files, file)
+		in.readers = append(in.readers, file)
+	}
+	return in, nil
+}
+
+// copyToTemp копирует файл во временный файл и возвращает копию, открытую с начала.
+// Исходный файл закрывается.
+func (in *inputs) copyToTemp(file *os.File, tempDir string) (*os.File, error) {
+	defer file.Close()
+
+	temp, err := os.CreateTemp(tempDir, "sort-input-")
+	if err != nil {
+		return nil, err
+	}
+	in.temps = append(in.temps, temp.Name())
+
+	if _, err := io.Copy(temp, file); err != nil {
+		temp.Close()
+		return nil, err
+	}
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		temp.Close()
+		return nil, err
+	}
+	return temp, nil
+}
+
+// close закрывает входные файлы и удаляет временные копии.
+func (in *inputs) close() {
+	for _, file := range in.files {
+		file.Close()
+	}
+	for _, name := range in.temps {
+		os.Remove(name)
+	}
+}
+
+// readLines читает строки из всех потоков по порядку.
+func readLines(readers []io.Reader) ([]string, error) {
+	var data []string
+	for _, r := range readers {
+		lines, err := linesort.ReadLines(r)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, lines...)
+	}
+	return data, nil
+}
+
+// createOutput открывает выходной файл; пустое имя означает stdout.
+// Возвращаемая функция закрывает файл и сообщает об ошибке записи.
+func createOutput(name string) (io.Writer, func() error, error) {
+	if name == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, file.Close, nil
+}
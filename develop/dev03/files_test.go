@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dev03/linesort"
+)
+
+// writeFile создаёт в каталоге dir файл name с содержимым content и возвращает путь к нему.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readFile возвращает содержимое файла path.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// setStdin подменяет os.Stdin файлом с содержимым content до конца теста.
+func setStdin(t *testing.T, content string) {
+	t.Helper()
+	file, err := os.Open(writeFile(t, t.TempDir(), "stdin", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = file
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		file.Close()
+	})
+}
+
+// emptyDir проверяет, что после сортировки в каталоге временных файлов ничего не осталось.
+func emptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("temporary files left in %s: %v", dir, entries)
+	}
+}
+
+func TestSortFilesOutputIsInput(t *testing.T) {
+	cmp, err := linesort.NewBuilder().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Входа больше, чем части -S, чтобы внешняя сортировка прочитала его не за один раз.
+	var unsorted, sorted strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&unsorted, "line%03d\n", (i*37)%200)
+		fmt.Fprintf(&sorted, "line%03d\n", i)
+	}
+
+	tests := []struct {
+		name   string
+		files  []string // Содержимое входных файлов
+		output int      // Номер входного файла, в который пишется результат
+		merge  bool
+		size   int64
+		want   string
+	}{
+		{
+			name:  "in memory",
+			files: []string{unsorted.String()},
+			want:  sorted.String(),
+		},
+		{
+			name:  "in memory with another input",
+			files: []string{"c\na\n", "b\n"},
+			want:  "a\nb\nc\n",
+		},
+		{
+			name:  "external sort",
+			files: []string{unsorted.String()},
+			size:  256,
+			want:  sorted.String(),
+		},
+		{
+			name:  "merge",
+			files: []string{"a\nc\ne\n", "b\nd\n"},
+			merge: true,
+			want:  "a\nb\nc\nd\ne\n",
+		},
+		{
+			name:   "merge into the second input",
+			files:  []string{"b\nd\n", "a\nc\n"},
+			output: 1,
+			merge:  true,
+			want:   "a\nb\nc\nd\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, tempDir := t.TempDir(), t.TempDir()
+			var names []string
+			for i, content := range tt.files {
+				names = append(names, writeFile(t, dir, fmt.Sprintf("in%d", i), content))
+			}
+			output := names[tt.output]
+
+			cfg := config{cmp: cmp, merge: tt.merge, output: output}
+			cfg.opts = linesort.Options{Parallel: 2, BufferSize: tt.size, TempDir: tempDir}
+			if err := sortFiles(context.Background(), names, cfg); err != nil {
+				t.Fatalf("sortFiles() = %v", err)
+			}
+			if got := readFile(t, output); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+			emptyDir(t, tempDir)
+		})
+	}
+}
+
+func TestSortFilesStdin(t *testing.T) {
+	cmp, err := linesort.NewBuilder().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a", "d\na\n")
+	b := writeFile(t, dir, "b", "c\n")
+
+	for _, size := range []int64{0, 1 << 10} {
+		setStdin(t, "e\nb\n")
+		output := filepath.Join(dir, "out")
+		cfg := config{cmp: cmp, output: output}
+		cfg.opts = linesort.Options{Parallel: 1, BufferSize: size, TempDir: t.TempDir()}
+		if err := sortFiles(context.Background(), []string{a, "-", b}, cfg); err != nil {
+			t.Fatalf("BufferSize=%d: sortFiles() = %v", size, err)
+		}
+		if got, want := readFile(t, output), "a\nb\nc\nd\ne\n"; got != want {
+			t.Errorf("BufferSize=%d: output = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestOpenInputs(t *testing.T) {
+	dir, tempDir := t.TempDir(), t.TempDir()
+	a := writeFile(t, dir, "a", "a\n")
+	b := writeFile(t, dir, "b", "b\n")
+	setStdin(t, "stdin\n")
+
+	// Без выходного файла ничего не копируется, "-" читается из stdin.
+	in, err := openInputs([]string{a, "-", b}, "", tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in.temps) != 0 || len(in.files) != 2 || len(in.readers) != 3 || in.readers[1] != os.Stdin {
+		t.Errorf("openInputs() without output: %d temps, %d files, %d readers", len(in.temps), len(in.files), len(in.readers))
+	}
+	in.close()
+
+	// Вход, совпадающий с выходом, читается из копии, даже если выход уже перезаписан.
+	in, err = openInputs([]string{a, b}, b, tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in.temps) != 1 {
+		t.Fatalf("openInputs() with output = input: %d temps, want 1", len(in.temps))
+	}
+	if err := os.WriteFile(b, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range in.readers {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(data))
+	}
+	if want := "a\n|b\n"; strings.Join(got, "|") != want {
+		t.Errorf("inputs = %q, want %q", strings.Join(got, "|"), want)
+	}
+	in.close()
+	emptyDir(t, tempDir)
+
+	// Несуществующий выходной файл ни с чем не совпадает.
+	in, err = openInputs([]string{a}, filepath.Join(dir, "new"), tempDir)
+	if err != nil || len(in.temps) != 0 {
+		t.Errorf("openInputs() with new output: %v, %d temps", err, len(in.temps))
+	}
+	in.close()
+
+	if _, err := openInputs([]string{a, filepath.Join(dir, "missing")}, "", tempDir); err == nil {
+		t.Error("openInputs() with a missing file: want error")
+	}
+}
+
+func TestParseBufferSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"100", 100 << 10},
+		{"100b", 100},
+		{"1k", 1 << 10},
+		{"1K", 1 << 10},
+		{"2M", 2 << 20},
+		{"3G", 3 << 30},
+		{"1T", 1 << 40},
+		{"4194304T", 1 << 62}, // Наибольший допустимый размер
+	}
+	for _, tt := range tests {
+		if got, err := parseBufferSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseBufferSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "0", "0M", "-1", "M", "1.5M", "10X", "4194305T", "9223372036854775807"} {
+		if _, err := parseBufferSize(in); err == nil {
+			t.Errorf("parseBufferSize(%q): want error", in)
+		}
+	}
+}
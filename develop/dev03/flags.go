@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	"dev03/linesort"
+
+	"github.com/pborman/getopt"
+)
+
+// maxDefaultParallel — верхняя граница числа потоков по умолчанию, как в GNU sort.
+const maxDefaultParallel = 8
+
+// defaultParallel возвращает число потоков сортировки по умолчанию.
+func defaultParallel() int {
+	return min(runtime.NumCPU(), maxDefaultParallel)
+}
+
+// validateParallel проверяет значение --parallel.
+func validateParallel(n int) error {
+	if n < 1 {
+		return fmt.Errorf("invalid number of threads %d: must be at least 1", n)
+	}
+	return nil
+}
+
+// parseBufferSize разбирает размер буфера -S: число с необязательным суффиксом b, K, M, G или T.
+// Число без суффикса означает килобайты, как в GNU sort.
+func parseBufferSize(s string) (int64, error) {
+	suffixes := map[byte]int64{'b': 1, 'k': 1 << 10, 'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+
+	digits, multiplier := s, int64(1<<10)
+	if s != "" {
+		if m, ok := suffixes[s[len(s)-1]]; ok {
+			digits, multiplier = s[:len(s)-1], m
+		}
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)/multiplier {
+		return 0, fmt.Errorf("invalid buffer size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// parseTab возвращает разделитель полей -t; \0 означает нулевой байт.
+// Остальные проверки выполняет linesort.Builder.Separator.
+func parseTab(s string) string {
+	if s == `\0` {
+		return "\x00"
+	}
+	return s
+}
+
+// keysValue накапливает ключи, заданные повторяющимся флагом -k.
+type keysValue []linesort.Key
+
+func (k *keysValue) Set(value string, _ getopt.Option) error {
+	parsed, err := linesort.ParseKey(value)
+	if err != nil {
+		return err
+	}
+	*k = append(*k, parsed)
+	return nil
+}
+
+func (k *keysValue) String() string {
+	return ""
+}
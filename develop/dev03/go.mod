@@ -2,4 +2,7 @@ module dev03
 
 go 1.22.3
 
-require github.com/pborman/getopt v1.1.0 
+require (
+	github.com/pborman/getopt v1.1.0
+	golang.org/x/text v0.22.0
+)
@@ -0,0 +1,68 @@
+package linesort
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// collator сравнивает строки по правилам Unicode Collation Algorithm для заданного языка.
+// collate.Collator нельзя использовать из нескольких горутин, поэтому у каждой горутины свой экземпляр.
+type collator struct {
+	pool sync.Pool
+}
+
+// newCollator создаёт collator для языка в формате BCP 47, например "ru" или "en".
+func newCollator(lang string) (*collator, error) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collation language %q", lang)
+	}
+	c := &collator{}
+	c.pool.New = func() any {
+		return collate.New(tag)
+	}
+	return c, nil
+}
+
+// compare возвращает отрицательное число, ноль или положительное число,
+// если a идёт раньше, вместе или позже b по правилам языка.
+func (c *collator) compare(a, b string) int {
+	coll := c.pool.Get().(*collate.Collator)
+	defer c.pool.Put(coll)
+	return coll.CompareString(a, b)
+}
+
+// translate применяет к ключу модификаторы d и f: убирает всё, кроме букв, цифр и пробелов,
+// и приводит буквы к верхнему регистру. Некорректные байты UTF-8 при свёртке регистра не меняются.
+func (m Modifiers) translate(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if m.Dictionary && !isDictionary(r) {
+			i += size
+			continue
+		}
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteByte(s[i])
+		case m.Fold:
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			b.WriteRune(r)
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// isDictionary сообщает, учитывается ли символ при сравнении в словарном порядке (-d).
+func isDictionary(r rune) bool {
+	return r == ' ' || r == '\t' || (r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+}
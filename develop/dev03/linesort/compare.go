@@ -0,0 +1,343 @@
+package linesort
+
+import (
+	"strings"
+)
+
+// Comparator сравнивает строки так же, как GNU sort в локали C или по правилам заданного языка.
+// Comparator создаётся Builder и безопасен для использования из нескольких горутин.
+type Comparator struct {
+	keys     []Key     // Ключи в порядке приоритета
+	tab      string    // Разделитель полей; пустая строка — переход от пробелов к непробельным символам
+	reverse  bool      // Обратный порядок для сравнения целых строк
+	unique   bool      // Строки с равными ключами считаются одинаковыми
+	stable   bool      // Строки с равными ключами не сравниваются целиком
+	collator *collator // Правила сравнения текста для языка; nil — побайтно
+}
+
+// Builder собирает Comparator из ключей и глобальных настроек. Методы возвращают сам Builder,
+// поэтому вызовы можно объединять в цепочку; ошибки накапливаются и возвращаются из Build.
+//
+//	cmp, err := linesort.NewBuilder().Separator(",").KeySpec("1,1", "3,3nr").Stable().Build()
+type Builder struct {
+	keys    []Key
+	global  Modifiers
+	blanks  bool
+	reverse bool
+	unique  bool
+	stable  bool
+	tab     string
+	lang    string
+	err     error
+}
+
+// NewBuilder создаёт Builder, который без настроек сравнивает строки целиком побайтно.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Key добавляет ключи в конец списка; раньше добавленные ключи важнее.
+func (b *Builder) Key(keys ...Key) *Builder {
+	for _, k := range keys {
+		if err := k.validate(); err != nil {
+			b.setErr(err)
+			continue
+		}
+		b.keys = append(b.keys, k)
+	}
+	return b
+}
+
+// KeySpec добавляет ключи, заданные в формате GNU sort -k, например "2,2n".
+func (b *Builder) KeySpec(specs ...string) *Builder {
+	for _, spec := range specs {
+		k, err := ParseKey(spec)
+		if err != nil {
+			b.setErr(err)
+			continue
+		}
+		b.keys = append(b.keys, k)
+	}
+	return b
+}
+
+// Modifiers задаёт модификаторы для ключей без собственных модификаторов (-n, -h, -M, -V, -d, -f).
+// Если ключей нет, модификаторы применяются ко всей строке.
+func (b *Builder) Modifiers(m Modifiers) *Builder {
+	b.global = m
+	return b
+}
+
+// IgnoreLeadingBlanks пропускает пробелы в начале ключей без собственных модификаторов (-b).
+func (b *Builder) IgnoreLeadingBlanks() *Builder {
+	b.blanks = true
+	return b
+}
+
+// Reverse меняет порядок на обратный для ключей без собственных модификаторов
+// и для сравнения строк целиком (-r).
+func (b *Builder) Reverse() *Builder {
+	b.reverse = true
+	return b
+}
+
+// Unique считает строки с равными ключами одинаковыми: Sort и Merge оставляют первую из них (-u).
+func (b *Builder) Unique() *Builder {
+	b.unique = true
+	return b
+}
+
+// Stable не сравнивает строки с равными ключами целиком, сохраняя их исходный порядок (-s).
+func (b *Builder) Stable() *Builder {
+	b.stable = true
+	return b
+}
+
+// Separator задаёт разделитель полей из одного символа вместо перехода от пробелов к непробельным (-t).
+func (b *Builder) Separator(tab string) *Builder {
+	if err := validateSeparator(tab); err != nil {
+		b.setErr(err)
+		return b
+	}
+	b.tab = tab
+	return b
+}
+
+// Collate сравнивает текст по правилам Unicode для языка в формате BCP 47, например "ru" или "en".
+func (b *Builder) Collate(lang string) *Builder {
+	b.lang = lang
+	return b
+}
+
+// setErr запоминает первую ошибку настройки.
+func (b *Builder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Build создаёт Comparator. Ключи без собственных модификаторов наследуют глобальные;
+// если ключей нет, но заданы глобальные модификаторы, ключом служит вся строка.
+// Как и в GNU sort, несовместимые глобальные модификаторы — ошибка, только если их наследует ключ.
+func (b *Builder) Build() (*Comparator, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	c := &Comparator{tab: b.tab, reverse: b.reverse, unique: b.unique, stable: b.stable}
+	if b.lang != "" {
+		coll, err := newCollator(b.lang)
+		if err != nil {
+			return nil, err
+		}
+		c.collator = coll
+	}
+
+	keys := b.keys
+	if len(keys) == 0 && (!b.global.isDefault() || b.blanks) {
+		keys = []Key{wholeLine()}
+	}
+	for _, k := range keys {
+		if k.isDefault() {
+			if err := b.global.validate(); err != nil {
+				return nil, err
+			}
+			k.Modifiers = b.global
+			k.SkipStart, k.SkipEnd = b.blanks, b.blanks
+			k.Reverse = b.reverse
+		}
+		c.keys = append(c.keys, k)
+	}
+	return c, nil
+}
+
+// Compare возвращает отрицательное число, ноль или положительное число,
+// если a меньше, равна или больше b соответственно.
+func (c *Comparator) Compare(a, b string) int {
+	if len(c.keys) > 0 {
+		if diff := c.compareKeys(a, b); diff != 0 || c.unique || c.stable {
+			return diff
+		}
+	}
+
+	// Если ключи равны, строки сравниваются целиком: по правилам языка, а при равенстве побайтно.
+	// С Stable и Unique этого не происходит, и равные строки остаются в исходном порядке.
+	diff := c.compareText(a, b)
+	if diff == 0 && c.collator != nil {
+		diff = strings.Compare(a, b)
+	}
+	if c.reverse {
+		return -diff
+	}
+	return diff
+}
+
+// Unique сообщает, что Sort и Merge оставляют из строк с равными ключами только первую.
+func (c *Comparator) Unique() bool {
+	return c.unique
+}
+
+// compareKeys сравнивает строки по ключам в порядке их приоритета.
+func (c *Comparator) compareKeys(a, b string) int {
+	for _, k := range c.keys {
+		ka, kb := k.extract(a, c.tab), k.extract(b, c.tab)
+		if k.Dictionary || k.Fold {
+			ka, kb = k.translate(ka), k.translate(kb)
+		}
+
+		var diff int
+		switch {
+		case k.Numeric:
+			diff = compareNumeric(ka, kb)
+		case k.Human:
+			diff = compareHuman(ka, kb)
+		case k.Month:
+			diff = getMonth(ka) - getMonth(kb)
+		case k.Version:
+			diff = compareVersion(ka, kb)
+		default:
+			diff = c.compareText(ka, kb)
+		}
+
+		if k.Reverse {
+			diff = -diff
+		}
+		if diff != 0 {
+			return diff
+		}
+	}
+	return 0
+}
+
+// compareText сравнивает текст по правилам языка или, если язык не задан, побайтно.
+func (c *Comparator) compareText(a, b string) int {
+	if c.collator != nil {
+		return c.collator.compare(a, b)
+	}
+	return strings.Compare(a, b)
+}
+
+// number — число, разобранное из начала строки: знак, целая часть без ведущих нулей
+// и дробная часть без хвостовых нулей. Так числа сравниваются без потери точности.
+type number struct {
+	negative bool
+	integer  string
+	fraction string
+	end      int // Смещение первого байта после числа
+}
+
+// parseNumber разбирает число в начале s после пробелов. Строка без числа равна нулю.
+func parseNumber(s string) number {
+	var n number
+	i := skipBlanks(s, 0)
+	if i < len(s) && s[i] == '-' {
+		n.negative = true
+		i++
+	}
+
+	start := i
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	n.integer = strings.TrimLeft(s[start:i], "0")
+
+	if i < len(s) && s[i] == '.' {
+		i++
+		start = i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		n.fraction = strings.TrimRight(s[start:i], "0")
+	}
+	n.end = i
+
+	if n.isZero() {
+		n.negative = false // -0 равен 0.
+	}
+	return n
+}
+
+// isZero сообщает, что число равно нулю.
+func (n number) isZero() bool {
+	return n.integer == "" && n.fraction == ""
+}
+
+// sign возвращает -1, 0 или 1 в зависимости от знака числа.
+func (n number) sign() int {
+	switch {
+	case n.isZero():
+		return 0
+	case n.negative:
+		return -1
+	}
+	return 1
+}
+
+// compareNumbers сравнивает два разобранных числа.
+func compareNumbers(a, b number) int {
+	if sa, sb := a.sign(), b.sign(); sa != sb {
+		return sa - sb
+	}
+
+	// Модули сравниваются по длине целой части, затем по цифрам.
+	diff := len(a.integer) - len(b.integer)
+	if diff == 0 {
+		diff = strings.Compare(a.integer, b.integer)
+	}
+	if diff == 0 {
+		diff = strings.Compare(a.fraction, b.fraction)
+	}
+	if a.negative {
+		return -diff
+	}
+	return diff
+}
+
+// compareNumeric сравнивает строки по числовому значению (-n).
+func compareNumeric(a, b string) int {
+	return compareNumbers(parseNumber(a), parseNumber(b))
+}
+
+// unitOrder — порядок суффиксов размеров для -h.
+var unitOrder = map[byte]int{
+	'k': 1, 'K': 1, 'M': 2, 'G': 3, 'T': 4, 'P': 5, 'E': 6, 'Z': 7, 'Y': 8, 'R': 9, 'Q': 10,
+}
+
+// compareHuman сравнивает числа с суффиксами размеров (-h): сначала по суффиксу, затем по числу.
+// Как и в GNU sort, 2K больше 1000000, потому что суффикс важнее значения.
+func compareHuman(a, b string) int {
+	na, nb := parseNumber(a), parseNumber(b)
+	if diff := humanOrder(a, na) - humanOrder(b, nb); diff != 0 {
+		return diff
+	}
+	return compareNumbers(na, nb)
+}
+
+// humanOrder возвращает порядок суффикса числа n, разобранного из s, со знаком числа.
+func humanOrder(s string, n number) int {
+	if n.isZero() || n.end >= len(s) {
+		return 0
+	}
+	order := unitOrder[s[n.end]]
+	if n.negative {
+		return -order
+	}
+	return order
+}
+
+// months — названия месяцев для -M.
+var months = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// getMonth возвращает номер месяца, с названия которого начинается строка, или 0.
+func getMonth(s string) int {
+	s = s[skipBlanks(s, 0):]
+	if len(s) < 3 {
+		return 0
+	}
+	for i, m := range months {
+		if strings.EqualFold(s[:3], m) {
+			return i + 1
+		}
+	}
+	return 0
+}
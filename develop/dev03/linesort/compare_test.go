@@ -0,0 +1,182 @@
+package linesort
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareVersion(t *testing.T) {
+	// Каждая строка меньше следующей.
+	ordered := []string{"", ".", "..", ".hidden", ".5", "1.2.9", "1.2.10", "1.10", "a", "a.", "file2", "file10", "x", "x.tar", "x.tar.gz"}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, b := ordered[i], ordered[i+1]
+		if diff := compareVersion(a, b); diff >= 0 {
+			t.Errorf("compareVersion(%q, %q) = %d, want < 0", a, b, diff)
+		}
+		if diff := compareVersion(b, a); diff <= 0 {
+			t.Errorf("compareVersion(%q, %q) = %d, want > 0", b, a, diff)
+		}
+	}
+	if diff := compareVersion("v01", "v1"); diff != 0 {
+		t.Errorf("compareVersion(v01, v1) = %d, want 0", diff)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		opts Modifiers
+		in   string
+		want string
+	}{
+		{Modifiers{Fold: true}, "Ёлка, tree!", "ЁЛКА, TREE!"},
+		{Modifiers{Dictionary: true}, "Ёлка, tree!", "Ёлка tree"},
+		{Modifiers{Dictionary: true, Fold: true}, "a-b\tв_1", "AB\tВ1"},
+		{Modifiers{Fold: true}, "a\xffb", "A\xffB"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.translate(tt.in); got != tt.want {
+			t.Errorf("%+v.translate(%q) = %q, want %q", tt.opts, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCollate(t *testing.T) {
+	lines := []string{"яблоко", "Жук", "ёж", "абрикос", "Банан", "Zebra", "apple"}
+	cmp, err := NewBuilder().Collate("ru").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Sort(slices.Clone(lines), cmp, Options{})
+	want := []string{"apple", "Zebra", "абрикос", "Банан", "ёж", "Жук", "яблоко"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Sort with Collate(ru) = %q, want %q", got, want)
+	}
+
+	if _, err := NewBuilder().Collate("!!").Build(); err == nil {
+		t.Error("Build with invalid language: want error")
+	}
+}
+
+func TestStableKeysWithSeparator(t *testing.T) {
+	lines := []string{"b,x,1", "a,y,2", "b,z,10", "a,w,2", "b,v,1"}
+	cmp, err := NewBuilder().Separator(",").KeySpec("1,1", "3,3nr").Stable().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Sort(slices.Clone(lines), cmp, Options{})
+	want := []string{"a,y,2", "a,w,2", "b,z,10", "b,x,1", "b,v,1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Sort -s -t, -k1,1 -k3,3nr = %q, want %q", got, want)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *Builder
+	}{
+		{"empty separator", NewBuilder().Separator("")},
+		{"multi-character separator", NewBuilder().Separator(",;")},
+		{"invalid key spec", NewBuilder().KeySpec("0,1")},
+		{"incompatible key modifiers", NewBuilder().KeySpec("1nM")},
+		{"invalid key", NewBuilder().Key(Key{})},
+		{"incompatible inherited modifiers", NewBuilder().Modifiers(Modifiers{Numeric: true, Month: true}).KeySpec("1,1")},
+	}
+	for _, tt := range tests {
+		if _, err := tt.b.Build(); err == nil {
+			t.Errorf("%s: Build() error = nil", tt.name)
+		}
+	}
+
+	// Несовместимые глобальные модификаторы допустимы, если их не наследует ни один ключ.
+	if _, err := NewBuilder().Modifiers(Modifiers{Numeric: true, Month: true}).KeySpec("1,1n").Build(); err != nil {
+		t.Errorf("Build() with own key modifiers: %v", err)
+	}
+}
+
+func TestCompareHuman(t *testing.T) {
+	// Каждая строка меньше следующей.
+	ordered := []string{"-1M", "-2K", "-3", "0", "512", "1000000", "0.5K", "1k", "1.5K", "2K", "1M", "2.5M", "3G", "1T"}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, b := ordered[i], ordered[i+1]
+		if diff := compareHuman(a, b); diff >= 0 {
+			t.Errorf("compareHuman(%q, %q) = %d, want < 0", a, b, diff)
+		}
+		if diff := compareHuman(b, a); diff <= 0 {
+			t.Errorf("compareHuman(%q, %q) = %d, want > 0", b, a, diff)
+		}
+	}
+	for _, pair := range [][2]string{{"1K", "1k"}, {"1K", "1.0K"}, {"1K", "1KB"}, {"0K", "0"}, {"", "0"}} {
+		if diff := compareHuman(pair[0], pair[1]); diff != 0 {
+			t.Errorf("compareHuman(%q, %q) = %d, want 0", pair[0], pair[1], diff)
+		}
+	}
+}
+
+func TestGetMonth(t *testing.T) {
+	tests := map[string]int{"jan": 1, "FEB": 2, "Mar 2024": 3, "  dec": 12, "JANUARY": 1, "ja": 0, "": 0, "foo": 0, "1 jan": 0}
+	for s, want := range tests {
+		if got := getMonth(s); got != want {
+			t.Errorf("getMonth(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+// TestSortHumanMonth сравнивает порядок с выводом LC_ALL=C sort -h и -M.
+func TestSortHumanMonth(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *Builder
+		in   []string
+		want []string
+	}{
+		{
+			name: "-h",
+			b:    NewBuilder().Modifiers(Modifiers{Human: true}),
+			in:   []string{"2K", "1000000", "2.5M", "3G", "1k", "-1M", "0", "512", "1.5K"},
+			want: []string{"-1M", "0", "512", "1000000", "1k", "1.5K", "2K", "2.5M", "3G"},
+		},
+		{
+			name: "-h -r",
+			b:    NewBuilder().Modifiers(Modifiers{Human: true}).Reverse(),
+			in:   []string{"2K", "1000000", "2.5M", "3G"},
+			want: []string{"3G", "2.5M", "2K", "1000000"},
+		},
+		{
+			name: "-h equal values",
+			b:    NewBuilder().Modifiers(Modifiers{Human: true}),
+			in:   []string{"1k", "1K", "1024", "", "0.5K", "1.0K", "+1K", "1KB", "2e3"},
+			want: []string{"", "+1K", "2e3", "1024", "0.5K", "1.0K", "1K", "1KB", "1k"},
+		},
+		{
+			name: "-k2h",
+			b:    NewBuilder().KeySpec("2h"),
+			in:   []string{"x 2K", "y 3G", "z 100"},
+			want: []string{"z 100", "x 2K", "y 3G"},
+		},
+		{
+			name: "-M",
+			b:    NewBuilder().Modifiers(Modifiers{Month: true}),
+			in:   []string{"Mar", "jan", " FEB", "foo", "DEC", "nov"},
+			want: []string{"foo", "jan", " FEB", "Mar", "nov", "DEC"},
+		},
+		{
+			name: "-M -r",
+			b:    NewBuilder().Modifiers(Modifiers{Month: true}).Reverse(),
+			in:   []string{"Mar", "jan", " FEB", "foo", "DEC", "nov"},
+			want: []string{"DEC", "nov", "Mar", " FEB", "jan", "foo"},
+		},
+		{
+			name: "-M unknown and prefixes",
+			b:    NewBuilder().Modifiers(Modifiers{Month: true}),
+			in:   []string{"JANUARY", "ja", "Janx", " dec", "", "xyz", "aug"},
+			want: []string{"", "ja", "xyz", "JANUARY", "Janx", "aug", " dec"},
+		},
+	}
+	for _, tt := range tests {
+		cmp := mustBuild(t, tt.b)
+		if got := Sort(slices.Clone(tt.in), cmp, Options{}); !slices.Equal(got, tt.want) {
+			t.Errorf("sort %s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
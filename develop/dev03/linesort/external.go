@@ -0,0 +1,240 @@
+package linesort
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// mergeFanIn — сколько отсортированных частей сливается за один проход,
+// чтобы не упереться в лимит открытых файлов.
+const mergeFanIn = 16
+
+// lineOverhead — оценка памяти, которую занимает строка сверх своих байт (заголовок строки и слайса).
+const lineOverhead = 32
+
+// SortStream сортирует строки из потоков readers по порядку и записывает результат в w.
+// Если opts.BufferSize больше нуля, данные читаются частями не больше BufferSize байт,
+// каждая часть сортируется и сохраняется во временный файл в opts.TempDir, после чего
+// части сливаются кучей; так сортируются потоки, которые не помещаются в память.
+// При отмене ctx сортировка прерывается, а временные файлы удаляются.
+func SortStream(ctx context.Context, w io.Writer, readers []io.Reader, cmp *Comparator, opts Options) error {
+	if opts.BufferSize <= 0 {
+		var lines []string
+		for _, r := range readers {
+			part, err := ReadLines(r)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, part...)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return WriteLines(w, Sort(lines, cmp, opts))
+	}
+
+	var (
+		chunk []string
+		size  int64
+		dir   string
+		runs  []string
+	)
+
+	// Временный каталог удаляется при любом выходе из функции, в том числе при ошибке и отмене.
+	defer func() {
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	flush := func() error {
+		if dir == "" {
+			var err error
+			if dir, err = os.MkdirTemp(opts.TempDir, "sort-"); err != nil {
+				return err
+			}
+		}
+
+		name := filepath.Join(dir, fmt.Sprintf("run-%d", len(runs)))
+		if err := writeRun(name, Sort(chunk, cmp, opts)); err != nil {
+			return err
+		}
+		runs = append(runs, name)
+		chunk, size = nil, 0
+		return nil
+	}
+
+	for _, r := range readers {
+		br := bufio.NewReader(r)
+		for {
+			line, err := readLine(br)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			chunk = append(chunk, line)
+			size += int64(len(line)) + lineOverhead
+			if size >= opts.BufferSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Всё поместилось в память — сортируем без временных файлов.
+	if len(runs) == 0 {
+		return WriteLines(w, Sort(chunk, cmp, opts))
+	}
+	if len(chunk) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	// Сливаем части группами, пока их не станет достаточно мало для последнего прохода.
+	// Результат группы встаёт на её место, чтобы равные строки сохранили исходный порядок.
+	for pass := 0; len(runs) > mergeFanIn; pass++ {
+		name := filepath.Join(dir, fmt.Sprintf("merge-%d", pass))
+		if err := mergeRunsToFile(ctx, name, runs[:mergeFanIn], cmp); err != nil {
+			return err
+		}
+		runs = append([]string{name}, runs[mergeFanIn:]...)
+	}
+	return mergeRuns(ctx, w, runs, cmp)
+}
+
+// writeRun сохраняет отсортированную часть во временный файл.
+func writeRun(name string, lines []string) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := WriteLines(file, lines); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// mergeRunsToFile сливает части в новый временный файл и удаляет исходные части.
+func mergeRunsToFile(ctx context.Context, name string, runs []string, cmp *Comparator) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := mergeRuns(ctx, file, runs, cmp); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	for _, run := range runs {
+		os.Remove(run)
+	}
+	return nil
+}
+
+// mergeRuns открывает отсортированные файлы и сливает их в w.
+func mergeRuns(ctx context.Context, w io.Writer, runs []string, cmp *Comparator) error {
+	readers := make([]io.Reader, 0, len(runs))
+	for _, run := range runs {
+		file, err := os.Open(run)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+	return MergeStream(ctx, w, readers, cmp)
+}
+
+// mergeItem — очередная строка одного из сливаемых потоков.
+type mergeItem struct {
+	line   string
+	source int // Номер потока; при равенстве строк раньше идёт поток с меньшим номером
+	reader *bufio.Reader
+}
+
+// mergeHeap — куча строк, упорядоченная компаратором.
+type mergeHeap struct {
+	items []mergeItem
+	cmp   *Comparator
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	if diff := h.cmp.Compare(h.items[i].line, h.items[j].line); diff != 0 {
+		return diff < 0
+	}
+	return h.items[i].source < h.items[j].source
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x any) { h.items = append(h.items, x.(mergeItem)) }
+
+func (h *mergeHeap) Pop() any {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
+
+// MergeStream сливает отсортированные потоки в w. Равные строки идут в порядке потоков;
+// если cmp.Unique(), из каждой группы равных строк выводится первая. При отмене ctx слияние прерывается.
+func MergeStream(ctx context.Context, w io.Writer, readers []io.Reader, cmp *Comparator) error {
+	h := &mergeHeap{cmp: cmp}
+	for i, r := range readers {
+		br := bufio.NewReader(r)
+		line, err := readLine(br)
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h.items = append(h.items, mergeItem{line: line, source: i, reader: br})
+	}
+	heap.Init(h)
+
+	bw := bufio.NewWriter(w)
+	var last string
+	written := false
+	for h.Len() > 0 {
+		item := &h.items[0]
+		if !cmp.unique || !written || cmp.Compare(last, item.line) != 0 {
+			bw.WriteString(item.line)
+			bw.WriteByte('\n')
+			last, written = item.line, true
+		}
+
+		// Заменяем вершину кучи следующей строкой того же потока.
+		line, err := readLine(item.reader)
+		if err == nil {
+			err = ctx.Err()
+		}
+		switch {
+		case err == io.EOF:
+			heap.Pop(h)
+		case err != nil:
+			return err
+		default:
+			item.line = line
+			heap.Fix(h, 0)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,281 @@
+package linesort
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Modifiers — модификаторы сравнения ключа. Их можно задать всем ключам сразу
+// (Builder.Modifiers) или отдельному ключу суффиксами в описании, как в GNU sort.
+type Modifiers struct {
+	Numeric    bool // n: сравнивать как числа
+	Human      bool // h: сравнивать как числа с суффиксами K, M, G...
+	Month      bool // M: сравнивать как названия месяцев
+	Version    bool // V: сравнивать как номера версий
+	Dictionary bool // d: учитывать только буквы, цифры и пробелы
+	Fold       bool // f: не различать регистр букв
+}
+
+// isDefault сообщает, что модификаторы сравнения не заданы.
+func (m Modifiers) isDefault() bool {
+	return m == Modifiers{}
+}
+
+// validate проверяет, что задан не более чем один способ сравнения.
+// Как и в GNU sort, d совместим с V, но не с числовыми способами и месяцами.
+func (m Modifiers) validate() error {
+	count := 0
+	for _, on := range []bool{m.Numeric, m.Human, m.Month, m.Version || m.Dictionary} {
+		if on {
+			count++
+		}
+	}
+	if count <= 1 {
+		return nil
+	}
+
+	var set string
+	for _, opt := range []struct {
+		on   bool
+		flag string
+	}{{m.Dictionary, "d"}, {m.Fold, "f"}, {m.Human, "h"}, {m.Month, "M"}, {m.Numeric, "n"}, {m.Version, "V"}} {
+		if opt.on {
+			set += opt.flag
+		}
+	}
+	return fmt.Errorf("options '-%s' are incompatible", set)
+}
+
+// Key — ключ сортировки: диапазон строки от начального поля и символа до конечного и модификаторы.
+// Поля и символы нумеруются с единицы, как в GNU sort. Ключ без собственных модификаторов,
+// пропуска пробелов и Reverse наследует глобальные настройки Builder.
+type Key struct {
+	StartField int  // Номер поля, с которого начинается ключ
+	StartChar  int  // Номер символа в начальном поле; 0 — с первого символа
+	EndField   int  // Номер поля, которым заканчивается ключ; 0 — до конца строки
+	EndChar    int  // Номер символа в конечном поле; 0 — до конца поля
+	SkipStart  bool // b у начала ключа: пропускать пробелы перед начальным символом
+	SkipEnd    bool // b у конца ключа: пропускать пробелы перед конечным символом
+	Reverse    bool // r: обратный порядок для ключа
+	Modifiers
+}
+
+// Fields возвращает ключ по полям с first по last включительно без модификаторов.
+// Если last равен 0, ключ продолжается до конца строки.
+func Fields(first, last int) Key {
+	return Key{StartField: first, EndField: last}
+}
+
+// wholeLine возвращает ключ, который охватывает всю строку.
+func wholeLine() Key {
+	return Fields(1, 0)
+}
+
+// isDefault сообщает, что у ключа нет собственных модификаторов; такой ключ наследует глобальные.
+func (k Key) isDefault() bool {
+	return !k.SkipStart && !k.SkipEnd && !k.Reverse && k.Modifiers.isDefault()
+}
+
+// validate проверяет номера полей и символов и совместимость модификаторов ключа.
+func (k Key) validate() error {
+	switch {
+	case k.StartField < 1:
+		return fmt.Errorf("field number is zero")
+	case k.StartChar < 0 || k.EndField < 0 || k.EndChar < 0:
+		return fmt.Errorf("negative position")
+	}
+	return k.Modifiers.validate()
+}
+
+// ParseKey разбирает описание ключа в формате GNU sort -k F1[.C1][OPTS][,F2[.C2][OPTS]],
+// например "2", "3,3nr" или "1.3b,1.5".
+func ParseKey(spec string) (Key, error) {
+	var k Key
+	start, end, hasEnd := strings.Cut(spec, ",")
+
+	rest, err := k.parsePos(start, &k.StartField, &k.StartChar, &k.SkipStart)
+	if err == nil && rest != "" {
+		err = fmt.Errorf("invalid key modifier %q", rest[0])
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid key %q: %w", spec, err)
+	}
+	if k.StartChar == 0 && strings.Contains(start, ".") {
+		return Key{}, fmt.Errorf("invalid key %q: character offset is zero", spec)
+	}
+
+	if hasEnd {
+		rest, err = k.parsePos(end, &k.EndField, &k.EndChar, &k.SkipEnd)
+		if err == nil && rest != "" {
+			err = fmt.Errorf("invalid key modifier %q", rest[0])
+		}
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid key %q: %w", spec, err)
+		}
+	}
+
+	if err := k.Modifiers.validate(); err != nil {
+		return Key{}, fmt.Errorf("invalid key %q: %w", spec, err)
+	}
+	return k, nil
+}
+
+// parsePos разбирает позицию F[.C][OPTS] и модификаторы после неё.
+// Возвращает неразобранный остаток строки.
+func (k *Key) parsePos(s string, field, char *int, skipBlanks *bool) (string, error) {
+	digits := func(s string) (int, string, error) {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return 0, s, fmt.Errorf("missing number in %q", s)
+		}
+		n, err := strconv.Atoi(s[:i])
+		return n, s[i:], err
+	}
+
+	n, s, err := digits(s)
+	if err != nil {
+		return s, err
+	}
+	if n == 0 {
+		return s, fmt.Errorf("field number is zero")
+	}
+	*field = n
+
+	if strings.HasPrefix(s, ".") {
+		if *char, s, err = digits(s[1:]); err != nil {
+			return s, err
+		}
+	}
+
+	for ; s != ""; s = s[1:] {
+		switch s[0] {
+		case 'b':
+			*skipBlanks = true
+		case 'n':
+			k.Numeric = true
+		case 'h':
+			k.Human = true
+		case 'M':
+			k.Month = true
+		case 'r':
+			k.Reverse = true
+		case 'V':
+			k.Version = true
+		case 'd':
+			k.Dictionary = true
+		case 'f':
+			k.Fold = true
+		default:
+			return s, nil
+		}
+	}
+	return s, nil
+}
+
+// isBlank сообщает, является ли байт пробельным разделителем полей.
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// extract возвращает часть строки, по которой сравнивается ключ.
+// Поля отделяются разделителем tab, а если он пустой — переходом от пробельных символов
+// к непробельным; в последнем случае пробелы входят в начало поля.
+func (k Key) extract(line, tab string) string {
+	begin := k.begin(line, tab)
+	end := len(line)
+	if k.EndField > 0 {
+		end = k.limit(line, tab)
+	}
+	if end < begin {
+		end = begin // Ключ, который заканчивается раньше начала, считается пустым.
+	}
+	return line[begin:end]
+}
+
+// begin возвращает смещение начала ключа в строке.
+func (k Key) begin(line, tab string) int {
+	ptr := 0
+	for field := 1; field < k.StartField && ptr < len(line); field++ {
+		if tab == "" {
+			ptr = skipField(line, ptr)
+			continue
+		}
+		ptr = skipTab(line, ptr, tab)
+		if ptr < len(line) {
+			ptr += len(tab)
+		}
+	}
+	if k.SkipStart {
+		ptr = skipBlanks(line, ptr)
+	}
+	return min(len(line), ptr+max(k.StartChar-1, 0))
+}
+
+// limit возвращает смещение конца ключа в строке.
+func (k Key) limit(line, tab string) int {
+	fields := k.EndField - 1
+	if k.EndChar == 0 {
+		fields++ // Ключ захватывает конечное поле целиком.
+	}
+
+	ptr := 0
+	for ; fields > 0 && ptr < len(line); fields-- {
+		if tab == "" {
+			ptr = skipField(line, ptr)
+			continue
+		}
+		// Разделитель после конечного поля, взятого целиком, в ключ не входит.
+		ptr = skipTab(line, ptr, tab)
+		if ptr < len(line) && (fields > 1 || k.EndChar != 0) {
+			ptr += len(tab)
+		}
+	}
+	if k.EndChar != 0 {
+		if k.SkipEnd {
+			ptr = skipBlanks(line, ptr)
+		}
+		ptr = min(len(line), ptr+k.EndChar)
+	}
+	return ptr
+}
+
+// skipField пропускает поле, начинающееся с позиции ptr, вместе с ведущими пробелами.
+func skipField(line string, ptr int) int {
+	ptr = skipBlanks(line, ptr)
+	for ptr < len(line) && !isBlank(line[ptr]) {
+		ptr++
+	}
+	return ptr
+}
+
+// skipTab возвращает смещение ближайшего разделителя tab, начиная с позиции ptr, или длину строки.
+func skipTab(line string, ptr int, tab string) int {
+	if i := strings.Index(line[ptr:], tab); i >= 0 {
+		return ptr + i
+	}
+	return len(line)
+}
+
+// validateSeparator проверяет, что разделитель полей — ровно один символ.
+func validateSeparator(s string) error {
+	switch {
+	case s == "":
+		return fmt.Errorf("empty tab")
+	case utf8.RuneCountInString(s) > 1:
+		return fmt.Errorf("multi-character tab %q", s)
+	}
+	return nil
+}
+
+// skipBlanks пропускает пробельные символы, начиная с позиции ptr.
+func skipBlanks(line string, ptr int) int {
+	for ptr < len(line) && isBlank(line[ptr]) {
+		ptr++
+	}
+	return ptr
+}
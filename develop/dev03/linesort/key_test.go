@@ -0,0 +1,165 @@
+package linesort
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Key
+	}{
+		{"2", Key{StartField: 2}},
+		{"1.2,1.3", Key{StartField: 1, StartChar: 2, EndField: 1, EndChar: 3}},
+		{"2.2b,2.2", Key{StartField: 2, StartChar: 2, EndField: 2, EndChar: 2, SkipStart: true}},
+		{"2,2.0b", Key{StartField: 2, EndField: 2, SkipEnd: true}},
+		{"3,3nr", Key{StartField: 3, EndField: 3, Reverse: true, Modifiers: Modifiers{Numeric: true}}},
+		{"1M,2", Key{StartField: 1, EndField: 2, Modifiers: Modifiers{Month: true}}},
+		{"2h", Key{StartField: 2, Modifiers: Modifiers{Human: true}}},
+	}
+	for _, tt := range tests {
+		if got, err := ParseKey(tt.spec); err != nil || got != tt.want {
+			t.Errorf("ParseKey(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "0", "1.0", "a", "1x", "1,2x", "1,", "1nM", "1.2.3"} {
+		if _, err := ParseKey(spec); err == nil {
+			t.Errorf("ParseKey(%q): want error", spec)
+		}
+	}
+}
+
+func TestKeyExtract(t *testing.T) {
+	tests := []struct {
+		spec string
+		tab  string
+		line string
+		want string
+	}{
+		{"1.2,1.3", "", "abcd", "bc"},
+		{"2", "", "a  b c", "  b c"}, // Без -t пробелы входят в начало поля
+		{"2,2", "", "a  b c", "  b"},
+		{"2b,2", "", "a  b c", "b"},
+		{"2.2,2.2", "", "a  xb", " "},
+		{"2.2b,2.2", "", "a  xb", ""}, // Начало после конца: ключ пустой
+		{"2,2.1b", "", "a  xb", "  x"},
+		{"2,2.1", ":", "b:xb:1", "x"},
+		{"1,1.5", ":", "a:bc", "a:bc"}, // Символы за концом поля берутся из следующих полей, как в GNU sort
+		{"2,3", ":", "a:b:c:d", "b:c"},
+		{"3", ":", "a:b", ""},
+		{"2.2,2.3", ":", "a::c", "c"},
+		{"2.2,2.3", ":", "a::", ""},
+	}
+	for _, tt := range tests {
+		key, err := ParseKey(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := key.extract(tt.line, tt.tab); got != tt.want {
+			t.Errorf("-t %q -k%s: extract(%q) = %q, want %q", tt.tab, tt.spec, tt.line, got, tt.want)
+		}
+	}
+}
+
+// TestSortKeys сравнивает порядок с выводом LC_ALL=C sort с теми же ключами.
+func TestSortKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *Builder
+		in   []string
+		want []string
+	}{
+		{
+			name: "-k1.2,1.3",
+			b:    NewBuilder().KeySpec("1.2,1.3"),
+			in:   []string{"bca", "abz", "cab", "aby", "bbb"},
+			want: []string{"cab", "bbb", "aby", "abz", "bca"},
+		},
+		{
+			name: "-k2",
+			b:    NewBuilder().KeySpec("2"),
+			in:   []string{"x   b", "y a", "z  c"},
+			want: []string{"x   b", "z  c", "y a"},
+		},
+		{
+			name: "-k2b",
+			b:    NewBuilder().KeySpec("2b"),
+			in:   []string{"x   b", "y a", "z  c"},
+			want: []string{"y a", "x   b", "z  c"},
+		},
+		{
+			name: "-b -k2,2",
+			b:    NewBuilder().KeySpec("2,2").IgnoreLeadingBlanks(),
+			in:   []string{"x   b", "y a", "z  c"},
+			want: []string{"y a", "x   b", "z  c"},
+		},
+		{
+			name: "-k2.2,2.2",
+			b:    NewBuilder().KeySpec("2.2,2.2"),
+			in:   []string{"a  xb", "b zc", "c ya"},
+			want: []string{"a  xb", "c ya", "b zc"},
+		},
+		{
+			name: "-k2.2b,2.2",
+			b:    NewBuilder().KeySpec("2.2b,2.2"),
+			in:   []string{"c ya", "b zc", "a  xb"},
+			want: []string{"a  xb", "b zc", "c ya"},
+		},
+		{
+			name: "-k2.1,2.1b",
+			b:    NewBuilder().KeySpec("2.1,2.1b"),
+			in:   []string{"1 b", "2  a", "3 c"},
+			want: []string{"2  a", "1 b", "3 c"},
+		},
+		{
+			name: "-k2,2.0b",
+			b:    NewBuilder().KeySpec("2,2.0b"),
+			in:   []string{"a x", "b  w", "c   v"},
+			want: []string{"c   v", "b  w", "a x"},
+		},
+		{
+			name: "-t: -k2.2,2.2",
+			b:    NewBuilder().Separator(":").KeySpec("2.2,2.2"),
+			in:   []string{"a:zb:1", "b:xa:2", "c:ya:3"},
+			want: []string{"b:xa:2", "c:ya:3", "a:zb:1"},
+		},
+		{
+			name: "-t: -k2,2.1",
+			b:    NewBuilder().Separator(":").KeySpec("2,2.1"),
+			in:   []string{"b:xb:1", "a:xa:2"},
+			want: []string{"a:xa:2", "b:xb:1"},
+		},
+		{
+			name: "-t: -k2,2.1 -s",
+			b:    NewBuilder().Separator(":").KeySpec("2,2.1").Stable(),
+			in:   []string{"b:xb:1", "a:xa:2"},
+			want: []string{"b:xb:1", "a:xa:2"},
+		},
+		{
+			name: "-t: -k1,1.3 -s",
+			b:    NewBuilder().Separator(":").KeySpec("1,1.3").Stable(),
+			in:   []string{"a:zz", "a:b"},
+			want: []string{"a:b", "a:zz"},
+		},
+		{
+			name: "-t: -k1.3,1.4 -s",
+			b:    NewBuilder().Separator(":").KeySpec("1.3,1.4").Stable(),
+			in:   []string{"ab:c", "ab:b"},
+			want: []string{"ab:b", "ab:c"},
+		},
+		{
+			name: "-t: -k1,1.5",
+			b:    NewBuilder().Separator(":").KeySpec("1,1.5"),
+			in:   []string{"a:bc", "a:ab", "b:aa"},
+			want: []string{"a:ab", "a:bc", "b:aa"},
+		},
+	}
+	for _, tt := range tests {
+		cmp := mustBuild(t, tt.b)
+		if got := Sort(slices.Clone(tt.in), cmp, Options{}); !slices.Equal(got, tt.want) {
+			t.Errorf("sort %s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package linesort
+
+import (
+	"sort"
+	"sync"
+)
+
+// minParallelLines — меньше строк на поток сортировать параллельно невыгодно.
+const minParallelLines = 1 << 12
+
+// sortStable сортирует строки устойчивой сортировкой в parallel потоков.
+// Данные делятся на непрерывные части, части сортируются одновременно и затем попарно сливаются.
+// При слиянии равные строки левой части идут раньше правой, поэтому результат совпадает
+// с последовательной сортировкой.
+func sortStable(data []string, cmp *Comparator, parallel int) []string {
+	parallel = min(parallel, len(data)/minParallelLines)
+	if parallel <= 1 {
+		sort.SliceStable(data, func(i, j int) bool {
+			return cmp.Compare(data[i], data[j]) < 0
+		})
+		return data
+	}
+
+	// bounds[i] и bounds[i+1] — границы i-й части.
+	bounds := make([]int, parallel+1)
+	for i := range bounds {
+		bounds[i] = i * len(data) / parallel
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		part := data[bounds[i]:bounds[i+1]]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sort.SliceStable(part, func(i, j int) bool {
+				return cmp.Compare(part[i], part[j]) < 0
+			})
+		}()
+	}
+	wg.Wait()
+
+	// Сливаем соседние части попарно, пока не останется одна; src и dst меняются местами.
+	src, dst := data, make([]string, len(data))
+	for len(bounds) > 2 {
+		next := []int{0}
+		for i := 0; i+1 < len(bounds); i += 2 {
+			if i+2 >= len(bounds) {
+				// Непарная последняя часть переносится как есть.
+				copy(dst[bounds[i]:], src[bounds[i]:bounds[i+1]])
+				next = append(next, bounds[i+1])
+				continue
+			}
+			lo, mid, hi := bounds[i], bounds[i+1], bounds[i+2]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				mergeStable(dst[lo:hi], src[lo:mid], src[mid:hi], cmp)
+			}()
+			next = append(next, hi)
+		}
+		wg.Wait()
+		bounds = next
+		src, dst = dst, src
+	}
+	return src
+}
+
+// mergeStable сливает отсортированные a и b в dst; при равенстве раньше идёт строка из a.
+func mergeStable(dst, a, b []string, cmp *Comparator) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if cmp.Compare(b[j], a[i]) < 0 {
+			dst[k] = b[j]
+			j++
+		} else {
+			dst[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
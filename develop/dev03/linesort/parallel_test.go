@@ -0,0 +1,97 @@
+package linesort
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// randomLines генерирует строки CSV-подобного вида с повторяющимися ключами,
+// чтобы устойчивость сортировки влияла на результат.
+func randomLines(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	units := []string{"", "K", "M", "G"}
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s %d.%d %d%s %d",
+			months[rng.Intn(len(months))], rng.Intn(100)-50, rng.Intn(10),
+			rng.Intn(1000), units[rng.Intn(len(units))], i)
+	}
+	return lines
+}
+
+func mustBuild(t testing.TB, b *Builder) *Comparator {
+	t.Helper()
+	cmp, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmp
+}
+
+func TestSortParallelMatchesSerial(t *testing.T) {
+	lines := randomLines(50000, 1)
+
+	tests := []struct {
+		name string
+		b    *Builder
+	}{
+		{"line", NewBuilder()},
+		{"numeric", NewBuilder().Modifiers(Modifiers{Numeric: true})},
+		{"month key", NewBuilder().KeySpec("1,1M")},
+		{"human key", NewBuilder().KeySpec("3,3h")},
+		{"numeric key unique", NewBuilder().KeySpec("2,2n").Unique()},
+		{"several keys", NewBuilder().KeySpec("1,1M", "2,2nr").Stable()},
+	}
+	for _, tt := range tests {
+		cmp := mustBuild(t, tt.b)
+		want := Sort(slices.Clone(lines), cmp, Options{})
+
+		for _, parallel := range []int{2, 3, 5, 8, 64} {
+			got := Sort(slices.Clone(lines), cmp, Options{Parallel: parallel})
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: Parallel=%d differs from serial sort", tt.name, parallel)
+			}
+		}
+	}
+}
+
+func TestMergeStable(t *testing.T) {
+	cmp := mustBuild(t, NewBuilder().KeySpec("1,1").Unique())
+	a := []string{"a 1", "b 1", "b 2"}
+	b := []string{"a 3", "b 3", "c 3"}
+	dst := make([]string, len(a)+len(b))
+	mergeStable(dst, a, b, cmp)
+
+	want := []string{"a 1", "a 3", "b 1", "b 2", "b 3", "c 3"}
+	if !slices.Equal(dst, want) {
+		t.Errorf("mergeStable() = %q, want %q", dst, want)
+	}
+}
+
+func benchmarkSort(b *testing.B, builder *Builder, parallel int) {
+	cmp := mustBuild(b, builder)
+	lines := randomLines(1<<18, 1)
+	data := make([]string, len(lines))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		copy(data, lines)
+		b.StartTimer()
+		Sort(data, cmp, Options{Parallel: parallel})
+	}
+}
+
+func BenchmarkSortSerial(b *testing.B)   { benchmarkSort(b, NewBuilder(), 1) }
+func BenchmarkSortParallel(b *testing.B) { benchmarkSort(b, NewBuilder(), runtime.NumCPU()) }
+
+func BenchmarkSortNumericKeySerial(b *testing.B) {
+	benchmarkSort(b, NewBuilder().KeySpec("2,2n"), 1)
+}
+
+func BenchmarkSortNumericKeyParallel(b *testing.B) {
+	benchmarkSort(b, NewBuilder().KeySpec("2,2n"), runtime.NumCPU())
+}
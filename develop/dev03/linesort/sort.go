@@ -0,0 +1,151 @@
+// Package linesort сортирует, проверяет и сливает строки по правилам GNU sort:
+// ключи по полям и символам, числовое, месячное и версионное сравнение, сравнение
+// по правилам языка, устойчивая и параллельная сортировка, внешняя сортировка больших потоков.
+package linesort
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Options — настройки сортировки, которые не влияют на результат. Нулевое значение
+// сортирует в одном потоке и целиком в памяти.
+type Options struct {
+	Parallel   int    // Число потоков сортировки; 0 и 1 — один поток
+	BufferSize int64  // Для SortStream: размер части в байтах для внешней сортировки; 0 — всё в памяти
+	TempDir    string // Для SortStream: каталог временных файлов; пустая строка — os.TempDir()
+}
+
+// Sort сортирует строки устойчивой сортировкой и возвращает результат; исходный слайс
+// используется как буфер. Если cmp.Unique(), из каждой группы равных строк остаётся первая.
+func Sort(lines []string, cmp *Comparator, opts Options) []string {
+	lines = sortStable(lines, cmp, opts.Parallel)
+	if !cmp.unique {
+		return lines
+	}
+	return dedup(lines, cmp)
+}
+
+// dedup оставляет первую строку из каждой группы соседних равных строк.
+func dedup(lines []string, cmp *Comparator) []string {
+	result := lines[:0]
+	for i, line := range lines {
+		if i == 0 || cmp.Compare(result[len(result)-1], line) != 0 {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// IsSorted сообщает, что строки отсортированы. Если cmp.Unique(), равные соседние строки
+// тоже считаются нарушением порядка.
+func IsSorted(lines []string, cmp *Comparator) bool {
+	for i := 1; i < len(lines); i++ {
+		if outOfOrder(lines[i-1], lines[i], cmp) {
+			return false
+		}
+	}
+	return true
+}
+
+// outOfOrder сообщает, что строка line не может идти после prev.
+func outOfOrder(prev, line string, cmp *Comparator) bool {
+	diff := cmp.Compare(prev, line)
+	return diff > 0 || (cmp.unique && diff == 0)
+}
+
+// DisorderError — первая строка потока, нарушающая порядок.
+type DisorderError struct {
+	Line int    // Номер строки (с единицы)
+	Text string // Текст строки
+}
+
+func (e *DisorderError) Error() string {
+	return fmt.Sprintf("%d: disorder: %s", e.Line, e.Text)
+}
+
+// CheckSorted читает поток и возвращает *DisorderError для первой строки, нарушающей порядок,
+// или ошибку чтения. Поток читается до первого нарушения и целиком в память не загружается.
+func CheckSorted(r io.Reader, cmp *Comparator) error {
+	br := bufio.NewReader(r)
+	var prev string
+	for n := 1; ; n++ {
+		line, err := readLine(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n > 1 && outOfOrder(prev, line, cmp) {
+			return &DisorderError{Line: n, Text: line}
+		}
+		prev = line
+	}
+}
+
+// Merge сливает отсортированные слайсы в один. Равные строки идут в порядке слайсов;
+// если cmp.Unique(), из каждой группы равных строк остаётся первая.
+func Merge(cmp *Comparator, sorted ...[]string) []string {
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	// Сливаем соседние слайсы попарно, пока не останется один.
+	for len(sorted) > 1 {
+		next := make([][]string, 0, (len(sorted)+1)/2)
+		for i := 0; i < len(sorted); i += 2 {
+			if i+1 == len(sorted) {
+				next = append(next, sorted[i])
+				continue
+			}
+			merged := make([]string, len(sorted[i])+len(sorted[i+1]))
+			mergeStable(merged, sorted[i], sorted[i+1], cmp)
+			next = append(next, merged)
+		}
+		sorted = next
+	}
+
+	result := append([]string(nil), sorted[0]...)
+	if !cmp.unique {
+		return result
+	}
+	return dedup(result, cmp)
+}
+
+// ReadLines читает строки потока без переводов строки. Последняя строка может не заканчиваться им.
+func ReadLines(r io.Reader) ([]string, error) {
+	var lines []string
+	br := bufio.NewReader(r)
+	for {
+		line, err := readLine(br)
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+}
+
+// WriteLines выводит строки, завершая каждую переводом строки.
+func WriteLines(w io.Writer, lines []string) error {
+	bw := bufio.NewWriter(w)
+	for _, line := range lines {
+		bw.WriteString(line)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// readLine читает строку без завершающего перевода строки. Последняя строка может не заканчиваться им.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimSuffix(line, "\n"), err
+}
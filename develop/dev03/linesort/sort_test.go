@@ -0,0 +1,190 @@
+package linesort
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *Builder
+		in   []string
+		want []string
+	}{
+		{
+			name: "bytes",
+			b:    NewBuilder(),
+			in:   []string{"b", "B", "a", "10", "9"},
+			want: []string{"10", "9", "B", "a", "b"},
+		},
+		{
+			name: "numeric reverse",
+			b:    NewBuilder().Modifiers(Modifiers{Numeric: true}).Reverse(),
+			in:   []string{"2", "10", "-1", "1.5"},
+			want: []string{"10", "2", "1.5", "-1"},
+		},
+		{
+			name: "key struct",
+			b:    NewBuilder().Key(Key{StartField: 2, EndField: 2, Modifiers: Modifiers{Month: true}}),
+			in:   []string{"x MAR", "y jan", "z Feb"},
+			want: []string{"y jan", "z Feb", "x MAR"},
+		},
+		{
+			name: "fields and version",
+			b:    NewBuilder().Key(Fields(1, 1)).Modifiers(Modifiers{Version: true}),
+			in:   []string{"file10 a", "file2 b", "file1 c"},
+			want: []string{"file1 c", "file2 b", "file10 a"},
+		},
+		{
+			name: "unique fold",
+			b:    NewBuilder().Modifiers(Modifiers{Fold: true}).Unique(),
+			in:   []string{"b", "A", "a", "B"},
+			want: []string{"A", "b"},
+		},
+	}
+	for _, tt := range tests {
+		cmp := mustBuild(t, tt.b)
+		got := Sort(slices.Clone(tt.in), cmp, Options{})
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Sort() = %q, want %q", tt.name, got, tt.want)
+		}
+		if !IsSorted(got, cmp) {
+			t.Errorf("%s: IsSorted(%q) = false", tt.name, got)
+		}
+	}
+}
+
+func TestIsSortedUnique(t *testing.T) {
+	cmp := mustBuild(t, NewBuilder().Unique())
+	if IsSorted([]string{"a", "a"}, cmp) {
+		t.Error("IsSorted with duplicates and Unique = true")
+	}
+	if !IsSorted([]string{"a", "a"}, mustBuild(t, NewBuilder())) {
+		t.Error("IsSorted with duplicates = false")
+	}
+}
+
+func TestCheckSorted(t *testing.T) {
+	cmp := mustBuild(t, NewBuilder().Modifiers(Modifiers{Numeric: true}))
+	if err := CheckSorted(strings.NewReader("1\n2\n10\n"), cmp); err != nil {
+		t.Errorf("CheckSorted(sorted) = %v", err)
+	}
+
+	err := CheckSorted(strings.NewReader("1\n10\n2\n3"), cmp)
+	var disorder *DisorderError
+	if !errors.As(err, &disorder) || disorder.Line != 3 || disorder.Text != "2" {
+		t.Errorf("CheckSorted(unsorted) = %v, want disorder at line 3", err)
+	}
+	// Сообщение как у GNU sort -c после "sort: имя:".
+	if want := "3: disorder: 2"; err.Error() != want {
+		t.Errorf("DisorderError = %q, want %q", err.Error(), want)
+	}
+
+	tests := []struct {
+		name string
+		b    *Builder
+		in   string
+		line int // Номер строки, нарушающей порядок; 0 — порядок не нарушен
+	}{
+		{"reverse", NewBuilder().Reverse(), "b\na\na\n", 0},
+		{"unique", NewBuilder().Unique(), "a\nb\nb\n", 3},
+		{"key", NewBuilder().KeySpec("2,2"), "x b\ny a\n", 2},
+		{"month", NewBuilder().Modifiers(Modifiers{Month: true}), "jan\nFeb\nmar\nfoo\n", 4},
+		{"human", NewBuilder().Modifiers(Modifiers{Human: true}), "1000000\n2K\n1M\n", 0},
+	}
+	for _, tt := range tests {
+		err := CheckSorted(strings.NewReader(tt.in), mustBuild(t, tt.b))
+		line := 0
+		if errors.As(err, &disorder) {
+			line = disorder.Line
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if line != tt.line {
+			t.Errorf("%s: CheckSorted() = %v, want disorder at line %d", tt.name, err, tt.line)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	cmp := mustBuild(t, NewBuilder().KeySpec("1,1").Stable())
+	got := Merge(cmp, []string{"a 1", "c 1"}, []string{"a 2", "b 2"}, nil, []string{"a 4", "d 4"})
+	want := []string{"a 1", "a 2", "a 4", "b 2", "c 1", "d 4"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Merge() = %q, want %q", got, want)
+	}
+
+	cmp = mustBuild(t, NewBuilder().KeySpec("1,1").Unique())
+	got = Merge(cmp, []string{"a 1", "c 1"}, []string{"a 2", "b 2"})
+	want = []string{"a 1", "b 2", "c 1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Merge() with Unique = %q, want %q", got, want)
+	}
+}
+
+func TestMergeStream(t *testing.T) {
+	cmp := mustBuild(t, NewBuilder().Modifiers(Modifiers{Numeric: true}))
+	var out bytes.Buffer
+	readers := []io.Reader{strings.NewReader("1\n5\n9"), strings.NewReader(""), strings.NewReader("2\n3\n10\n")}
+	if err := MergeStream(context.Background(), &out, readers, cmp); err != nil {
+		t.Fatal(err)
+	}
+	if want := "1\n2\n3\n5\n9\n10\n"; out.String() != want {
+		t.Errorf("MergeStream() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestSortStream(t *testing.T) {
+	lines := randomLines(20000, 2)
+	half := len(lines) / 2
+	input := func() []io.Reader {
+		return []io.Reader{
+			strings.NewReader(strings.Join(lines[:half], "\n")), // Без перевода строки в конце
+			strings.NewReader(strings.Join(lines[half:], "\n") + "\n"),
+		}
+	}
+
+	for _, b := range []*Builder{NewBuilder(), NewBuilder().KeySpec("2,2n").Unique(), NewBuilder().KeySpec("1,1M").Reverse()} {
+		cmp := mustBuild(t, b)
+		var want bytes.Buffer
+		WriteLines(&want, Sort(slices.Clone(lines), cmp, Options{}))
+
+		// Маленький буфер даёт больше частей, чем сливается за один проход.
+		for _, opts := range []Options{{}, {BufferSize: 16 << 10, TempDir: t.TempDir()}} {
+			var out bytes.Buffer
+			if err := SortStream(context.Background(), &out, input(), cmp, opts); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), want.Bytes()) {
+				t.Errorf("SortStream(BufferSize=%d) differs from Sort", opts.BufferSize)
+			}
+			if opts.TempDir != "" {
+				if entries, _ := os.ReadDir(opts.TempDir); len(entries) != 0 {
+					t.Errorf("SortStream left %d temporary entries", len(entries))
+				}
+			}
+		}
+	}
+}
+
+func TestSortStreamCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dir := t.TempDir()
+	r := strings.NewReader(strings.Join(randomLines(10000, 3), "\n"))
+	err := SortStream(ctx, io.Discard, []io.Reader{r}, mustBuild(t, NewBuilder()), Options{BufferSize: 1 << 10, TempDir: dir})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("SortStream() = %v, want context.Canceled", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("SortStream left %d temporary entries", len(entries))
+	}
+}
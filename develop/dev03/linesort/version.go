@@ -0,0 +1,125 @@
+package linesort
+
+// compareVersion сравнивает строки как номера версий (-V) по алгоритму filevercmp из gnulib:
+// числа внутри строк сравниваются по значению, поэтому file2 < file10 и 1.2.9 < 1.2.10.
+func compareVersion(a, b string) int {
+	// Пустая строка идёт первой.
+	if a == "" || b == "" {
+		return boolToInt(b == "") - boolToInt(a == "")
+	}
+
+	// Строки с ведущей точкой идут раньше остальных: сначала ".", затем "..", затем прочие.
+	if a[0] == '.' || b[0] == '.' {
+		if a[0] != '.' {
+			return 1
+		}
+		if b[0] != '.' {
+			return -1
+		}
+		for _, special := range []string{".", ".."} {
+			if a == special || b == special {
+				return boolToInt(b == special) - boolToInt(a == special)
+			}
+		}
+	}
+
+	// Сначала строки сравниваются без суффиксов вида ".tar.gz", затем целиком.
+	aPrefix, bPrefix := versionPrefixLen(a), versionPrefixLen(b)
+	diff := verrevcmp(a[:aPrefix], b[:bPrefix])
+	if diff != 0 || (aPrefix == len(a) && bPrefix == len(b)) {
+		return diff
+	}
+	return verrevcmp(a, b)
+}
+
+// versionPrefixLen возвращает длину строки без самого длинного суффикса (\.[A-Za-z~][A-Za-z0-9~]*)*$.
+// Суффиксом может оказаться и вся строка, например ".hidden".
+func versionPrefixLen(s string) int {
+	for i := 0; ; i++ {
+		prefix := i
+		for i+1 < len(s) && s[i] == '.' && (isAlpha(s[i+1]) || s[i+1] == '~') {
+			for i += 2; i < len(s) && (isAlpha(s[i]) || isDigit(s[i]) || s[i] == '~'); i++ {
+			}
+		}
+		if i >= len(s) {
+			return prefix
+		}
+	}
+}
+
+// versionOrder возвращает вес байта s[pos] при сравнении нецифровых частей версий:
+// конец строки идёт раньше всего, кроме '~', буквы раньше прочих символов.
+func versionOrder(s string, pos int) int {
+	if pos >= len(s) {
+		return -1
+	}
+	switch c := s[pos]; {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -2
+	default:
+		return int(c) + 256
+	}
+}
+
+// verrevcmp сравнивает версии по правилам Debian: чередующиеся нецифровые и цифровые части,
+// нецифровые — посимвольно с весами versionOrder, цифровые — как числа.
+func verrevcmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			if ca, cb := versionOrder(a, i), versionOrder(b, j); ca != cb {
+				return ca - cb
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+
+		firstDiff := 0
+		for i < len(a) && j < len(b) && isDigit(a[i]) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+// isDigit сообщает, является ли байт цифрой ASCII.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isAlpha сообщает, является ли байт латинской буквой.
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// boolToInt возвращает 1 для true и 0 для false.
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
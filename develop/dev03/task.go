@@ -1,11 +1,14 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"sort"
-	"strconv"
+	"os/signal"
+	"syscall"
+
+	"dev03/linesort"
 
 	"github.com/pborman/getopt"
 )
@@ -35,81 +38,181 @@ import (
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
-// getFile читает строки из указанного файла и возвращает их в виде слайса строк.
-// Если uniqueRequired установлено в true, то удаляет повторяющиеся строки.
-func getFile(path string, uniqueRequired bool) ([]string, error) {
-	file, err := os.Open(path)
+// config — настройки сортировки из командной строки.
+type config struct {
+	cmp    *linesort.Comparator
+	opts   linesort.Options // --parallel, -S, -T
+	merge  bool             // -m: входные данные уже отсортированы, их нужно только слить
+	output string           // -o; пустая строка — stdout
+}
+
+// streaming сообщает, что входные данные читаются одновременно с записью результата.
+func (c config) streaming() bool {
+	return c.merge || c.opts.BufferSize > 0
+}
+
+// sortFiles сортирует или сливает входные файлы и записывает результат.
+// При потоковой обработке ctx прерывает работу, а временные файлы удаляются.
+func sortFiles(ctx context.Context, names []string, cfg config) (err error) {
+	// Без потоковой обработки всё читается до открытия выходного файла,
+	// поэтому копировать совпадающие с ним входные файлы не нужно.
+	clash := ""
+	if cfg.streaming() {
+		clash = cfg.output
+	}
+	in, err := openInputs(names, clash, cfg.opts.TempDir)
 	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	set := make(map[string]struct{})
-	scanner := bufio.NewScanner(file)
-	data := make([]string, 0)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if uniqueRequired {
-			if _, ok := set[line]; !ok {
-				set[line] = struct{}{}
-			} else {
-				continue
-			}
+		return err
+	}
+	defer in.close()
+
+	var data []string
+	if !cfg.streaming() {
+		if data, err = readLines(in.readers); err != nil {
+			return err
 		}
-		data = append(data, line)
+		data = linesort.Sort(data, cfg.cmp, cfg.opts)
+	}
+
+	w, closeOutput, err := createOutput(cfg.output)
+	if err != nil {
+		return err
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	defer func() {
+		if closeErr := closeOutput(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	switch {
+	case cfg.merge:
+		return linesort.MergeStream(ctx, w, in.readers, cfg.cmp)
+	case cfg.opts.BufferSize > 0:
+		return linesort.SortStream(ctx, w, in.readers, cfg.cmp, cfg.opts)
 	}
-	return data, nil
+	return linesort.WriteLines(w, data)
 }
 
-// toSort сортирует строки. Если n установлено в true, то сортирует по числовому значению.
-func toSort(data []string, n bool) []string {
-	if n {
-		sort.Slice(data, func(i, j int) bool {
-			vi, err1 := strconv.Atoi(data[i])
-			vj, err2 := strconv.Atoi(data[j])
-			if err1 != nil || err2 != nil {
-				// Если строки не могут быть преобразованы в числа, сортируем как строки.
-				return data[i] < data[j]
-			}
-			return vi < vj
-		})
-	} else {
-		sort.Slice(data, func(i, j int) bool {
-			return data[i] < data[j]
-		})
-	}
-	return data
+// checkFile проверяет, что файл отсортирован. Нарушение порядка выводится в stderr.
+func checkFile(name string, cmp *linesort.Comparator) (bool, error) {
+	in, err := openInputs([]string{name}, "", "")
+	if err != nil {
+		return false, err
+	}
+	defer in.close()
+
+	err = linesort.CheckSorted(in.readers[0], cmp)
+	var disorder *linesort.DisorderError
+	if errors.As(err, &disorder) {
+		fmt.Fprintf(os.Stderr, "sort: %s:%v\n", name, disorder)
+		return false, nil
+	}
+	return err == nil, err
 }
 
 func main() {
 	// Парсинг аргументов командной строки.
-	filename := getopt.String('f', "", "файл")
-	n := getopt.Bool('n', "сортировка по числовому значению")
+	var keys keysValue
+	var mods linesort.Modifiers
+	getopt.SetParameters("[file ...]")
+	getopt.Var(&keys, 'k', "ключ сортировки F1[.C1][OPTS][,F2[.C2][OPTS]], можно указать несколько раз")
+	getopt.BoolVar(&mods.Numeric, 'n', "сортировка по числовому значению")
+	getopt.BoolVar(&mods.Month, 'M', "сортировка по названию месяца")
+	getopt.BoolVar(&mods.Human, 'h', "сортировка по числовому значению с учётом суффиксов")
+	getopt.BoolVar(&mods.Version, 'V', "сортировка по номерам версий (file2 < file10)")
+	getopt.BoolVar(&mods.Dictionary, 'd', "учитывать только буквы, цифры и пробелы")
+	getopt.BoolVar(&mods.Fold, 'f', "не различать регистр букв")
+	lang := getopt.StringLong("collate", 0, "", "сравнивать текст по правилам Unicode для языка (например, ru или en)")
 	r := getopt.Bool('r', "сортировка в обратном порядке")
+	stable := getopt.Bool('s', "устойчивая сортировка: строки с равными ключами остаются в исходном порядке")
+	tab := getopt.String('t', "", "разделитель полей вместо перехода от пробелов к непробельным символам")
 	u := getopt.Bool('u', "не выводить повторяющиеся строки")
+	b := getopt.Bool('b', "игнорировать ведущие пробелы")
+	c := getopt.Bool('c', "проверить, отсортированы ли данные")
+	m := getopt.Bool('m', "слить уже отсортированные файлы без сортировки")
+	output := getopt.String('o', "", "записать результат в файл вместо stdout; файл может быть одним из входных")
+	bufferSize := getopt.String('S', "", "сортировать частями не больше указанного размера (например, 100M) через временные файлы")
+	tempDir := getopt.String('T', os.TempDir(), "каталог для временных файлов")
+	parallel := getopt.IntLong("parallel", 0, defaultParallel(), "число потоков сортировки")
 	getopt.Parse()
 
-	// Получение строк из файла.
-	file, err := getFile(*filename, *u)
-	if err != nil {
-		panic(err)
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, "sort:", err)
+		os.Exit(2)
 	}
 
-	// Сортировка строк.
-	file = toSort(file, *n)
+	if err := validateParallel(*parallel); err != nil {
+		fail(err)
+	}
 
-	// Вывод отсортированных строк.
+	// Без файлов читается stdin.
+	names := getopt.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+
+	builder := linesort.NewBuilder().Key(keys...).Modifiers(mods).Collate(*lang)
+	if *b {
+		builder.IgnoreLeadingBlanks()
+	}
 	if *r {
-		for i := len(file) - 1; i >= 0; i-- {
-			fmt.Println(file[i])
+		builder.Reverse()
+	}
+	if *u {
+		builder.Unique()
+	}
+	if *stable {
+		builder.Stable()
+	}
+	if getopt.IsSet('t') {
+		builder.Separator(parseTab(*tab))
+	}
+	cmp, err := builder.Build()
+	if err != nil {
+		fail(err)
+	}
+
+	// Проверка порядка строк вместо сортировки.
+	if *c {
+		if *output != "" {
+			fail(fmt.Errorf("options '-co' are incompatible"))
+		}
+		if len(names) > 1 {
+			fail(fmt.Errorf("extra operand %q not allowed with -c", names[1]))
 		}
-	} else {
-		for _, value := range file {
-			fmt.Println(value)
+		sorted, err := checkFile(names[0], cmp)
+		if err != nil {
+			fail(err)
+		}
+		if !sorted {
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg := config{cmp: cmp, merge: *m, output: *output}
+	cfg.opts = linesort.Options{Parallel: *parallel, TempDir: *tempDir}
+	if *bufferSize != "" {
+		size, err := parseBufferSize(*bufferSize)
+		if err != nil {
+			fail(err)
+		}
+		cfg.opts.BufferSize = size
+	}
+
+	// Потоковая обработка по сигналу прерывания останавливается и удаляет временные файлы;
+	// сортировка в памяти прерывается сигналом как обычно.
+	ctx := context.Background()
+	if cfg.streaming() {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
+
+	if err := sortFiles(ctx, names, cfg); err != nil {
+		if ctx.Err() != nil {
+			os.Exit(130)
 		}
+		fail(err)
 	}
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dev03/linesort"
+)
+
+func TestCheckFile(t *testing.T) {
+	cmp, err := linesort.NewBuilder().Modifiers(linesort.Modifiers{Numeric: true}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	name := writeFile(t, dir, "in", "1\n10\n2\n3\n")
+
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	saved := os.Stderr
+	os.Stderr = stderr
+	sorted, err := checkFile(name, cmp)
+	os.Stderr = saved
+
+	if sorted || err != nil {
+		t.Errorf("checkFile(unsorted) = %v, %v; want false, nil", sorted, err)
+	}
+	// Формат сообщения как у GNU sort -c.
+	if got, want := readFile(t, stderr.Name()), "sort: "+name+":3: disorder: 2\n"; got != want {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+
+	if sorted, err := checkFile(writeFile(t, dir, "sorted", "1\n2\n10\n"), cmp); !sorted || err != nil {
+		t.Errorf("checkFile(sorted) = %v, %v; want true, nil", sorted, err)
+	}
+	if _, err := checkFile(filepath.Join(dir, "missing"), cmp); err == nil {
+		t.Error("checkFile(missing) = nil error")
+	}
+}
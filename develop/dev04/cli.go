@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// Коды выхода командной строки.
+const (
+	exitOK    = 0 // Словари обработаны
+	exitIO    = 1 // Ошибка чтения или записи
+	exitUsage = 2 // Неверные аргументы командной строки
+)
+
+const usage = `usage: dev04 [-format json|tsv] [-norm none|nfc|nfd] [-fold] [-yo] [-letters] [-save index] [-serve addr] [file ...]
+       dev04 -load index [-format json|tsv] [-save index] [-serve addr]
+
+Читает словари (по одному слову в строке) из файлов или stdin, если файлы не заданы
+или имя файла "-", и выводит множества анаграмм. С -load индекс загружается из файла,
+сохранённого с -save, вместе с правилами нормализации.
+
+С -save и -serve множества анаграмм не выводятся. -serve запускает HTTP JSON API:
+
+  GET /anagrams?word=пятак       анаграммы слова
+  GET /subanagrams?letters=пятак слова, составленные из части букв
+  GET /prefix?q=пят              слова с заданным префиксом
+
+Все запросы принимают необязательный параметр limit.
+`
+
+// writer выводит множества анаграмм в одном из форматов.
+type writer func(w io.Writer, sets []AnagramSet) error
+
+// writers — поддерживаемые форматы вывода.
+var writers = map[string]writer{
+	"json": writeJSON,
+	"tsv":  writeTSV,
+}
+
+// run читает словари, ищет анаграммы и возвращает код выхода.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dev04", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "json", "формат вывода: json или tsv")
+	form := fs.String("norm", "none", "нормализация Unicode: none, nfc или nfd")
+	var opts Options
+	fs.BoolVar(&opts.FoldCase, "fold", false, "полное свёртывание регистра Unicode вместо нижнего регистра")
+	fs.BoolVar(&opts.FoldYo, "yo", false, "считать «ё» буквой «е»")
+	fs.BoolVar(&opts.LettersOnly, "letters", false, "игнорировать символы, не являющиеся буквами")
+	load := fs.String("load", "", "загрузить индекс из файла вместо чтения словарей")
+	save := fs.String("save", "", "сохранить индекс в файл")
+	addr := fs.String("serve", "", "запустить HTTP API на адресе, например localhost:8080")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return exitUsage
+	}
+	var err error
+	if opts.Form, err = parseForm(*form); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	if *load != "" && fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "-load cannot be combined with input files")
+		return exitUsage
+	}
+
+	index, err := buildIndex(fs.Args(), *load, opts, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitIO
+	}
+	if *save != "" {
+		if err := saveIndexFile(index, *save); err != nil {
+			fmt.Fprintln(stderr, "error:", err)
+			return exitIO
+		}
+	}
+	if *addr != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := serve(ctx, *addr, newHandler(newLookup(index)), stderr); err != nil {
+			fmt.Fprintln(stderr, "error:", err)
+			return exitIO
+		}
+		return exitOK
+	}
+	if *save != "" {
+		return exitOK
+	}
+
+	out := bufio.NewWriter(stdout)
+	err = write(out, index.sets())
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitIO
+	}
+	return exitOK
+}
+
+// buildIndex загружает индекс из файла load или строит его по словарям files с правилами opts.
+// Без файлов словарь читается из stdin.
+func buildIndex(files []string, load string, opts Options, stdin io.Reader) (*anagramIndex, error) {
+	if load != "" {
+		return loadIndexFile(load)
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	index := newAnagramIndex(opts)
+	if err := readFiles(index, files, stdin); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// readFiles добавляет в индекс слова из файлов по порядку; "-" означает stdin.
+func readFiles(index *anagramIndex, files []string, stdin io.Reader) error {
+	for _, name := range files {
+		if name == "-" {
+			if err := index.readFrom(stdin); err != nil {
+				return fmt.Errorf("stdin: %w", err)
+			}
+			continue
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = index.readFrom(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// writeJSON выводит множества массивом JSON в порядке словаря.
+func writeJSON(w io.Writer, sets []AnagramSet) error {
+	if sets == nil {
+		sets = []AnagramSet{} // Пустой результат выводится как [], а не null.
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sets)
+}
+
+// writeTSV выводит по строке на множество в порядке словаря: ключ и слова через табуляцию.
+func writeTSV(w io.Writer, sets []AnagramSet) error {
+	for _, set := range sets {
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", set.Key, strings.Join(set.Words, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
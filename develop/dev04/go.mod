@@ -1,3 +1,5 @@
 module dev04
 
 go 1.22.3
+
+require golang.org/x/text v0.22.0
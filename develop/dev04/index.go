@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strings"
+)
+
+// AnagramSet — множество анаграмм: ключ — первое встретившееся в словаре слово множества,
+// слова отсортированы по возрастанию.
+type AnagramSet struct {
+	Key   string   `json:"key"`
+	Words []string `json:"words"`
+}
+
+// anagramIndex накапливает слова по одному и группирует их по нормализованной форме.
+// Каждое уникальное слово хранится один раз, поэтому память растёт с числом уникальных слов,
+// а не с размером входных данных.
+type anagramIndex struct {
+	groups map[string][]string // Нормализованная форма -> слова группы в порядке появления
+	order  []string            // Нормализованные формы в порядке появления первого слова группы
+	seen   map[string]struct{} // Уже добавленные слова в нижнем регистре
+	opts   Options             // Правила приведения слов к общему виду
+}
+
+// newAnagramIndex создаёт пустой индекс, сравнивающий слова по правилам opts.
+func newAnagramIndex(opts Options) *anagramIndex {
+	return &anagramIndex{
+		opts:   opts,
+		groups: make(map[string][]string),
+		seen:   make(map[string]struct{}),
+	}
+}
+
+// add добавляет слово в индекс; повторы слова пропускаются.
+// Слова без букв для сравнения (с LettersOnly — «123», «--») ни с чем не образуют анаграмм и пропускаются.
+func (idx *anagramIndex) add(word string) {
+	word = idx.opts.word(word)
+	if _, exists := idx.seen[word]; exists {
+		return
+	}
+	normalized := normalizeWord(word, idx.opts)
+	if normalized == "" {
+		return
+	}
+	idx.seen[word] = struct{}{}
+
+	group, exists := idx.groups[normalized]
+	if !exists {
+		idx.order = append(idx.order, normalized)
+	}
+	idx.groups[normalized] = append(group, word)
+}
+
+// readFrom добавляет в индекс слова из потока: по одному слову в строке,
+// пробелы по краям и пустые строки пропускаются.
+func (idx *anagramIndex) readFrom(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			idx.add(word)
+		}
+	}
+	return scanner.Err()
+}
+
+// sets возвращает множества анаграмм в порядке словаря, исключая множества с одним элементом.
+func (idx *anagramIndex) sets() []AnagramSet {
+	var sets []AnagramSet
+	for _, normalized := range idx.order {
+		group := idx.groups[normalized]
+		if len(group) < 2 { // Исключение множеств с одним элементом
+			continue
+		}
+		words := slices.Clone(group)
+		slices.Sort(words) // Сортировка слов в множестве по возрастанию
+		sets = append(sets, AnagramSet{Key: group[0], Words: words})
+	}
+	return sets
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// lookup отвечает на запросы к готовому индексу анаграмм.
+// После создания индекс не меняется, поэтому lookup безопасен для конкурентного использования.
+type lookup struct {
+	index *anagramIndex
+	words []string // Все слова словаря по возрастанию, для поиска по префиксу
+}
+
+// newLookup создаёт lookup по индексу. Индекс нельзя изменять после вызова.
+func newLookup(index *anagramIndex) *lookup {
+	words := make([]string, 0, len(index.seen))
+	for word := range index.seen {
+		words = append(words, word)
+	}
+	slices.Sort(words)
+	return &lookup{index: index, words: words}
+}
+
+// anagrams возвращает анаграммы слова из словаря по возрастанию, не включая само слово.
+func (l *lookup) anagrams(word string) []string {
+	word = l.index.opts.word(word)
+	var result []string
+	for _, w := range l.index.groups[normalizeWord(word, l.index.opts)] {
+		if w != word {
+			result = append(result, w)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// subAnagrams возвращает слова словаря, которые можно составить из части букв letters
+// (каждую букву можно использовать столько раз, сколько она встречается в letters).
+// Слова упорядочены от длинных к коротким, слова одной длины — по возрастанию.
+func (l *lookup) subAnagrams(letters string) []string {
+	runes := []rune(normalizeWord(letters, l.index.opts))
+	if len(runes) == 0 {
+		return nil
+	}
+
+	var result []string
+	collect := func(key string) {
+		result = append(result, l.index.groups[key]...)
+	}
+	// Если подмножеств букв меньше, чем групп в индексе, перебираем подмножества,
+	// иначе проверяем каждую группу.
+	if counts := runLengths(runes); subsetsAtMost(counts, len(l.index.groups)) {
+		forEachSubset(counts, collect)
+	} else {
+		for key := range l.index.groups {
+			if key != "" && containsAll(runes, key) {
+				collect(key)
+			}
+		}
+	}
+
+	slices.SortFunc(result, func(a, b string) int {
+		if diff := utf8.RuneCountInString(b) - utf8.RuneCountInString(a); diff != 0 {
+			return diff
+		}
+		return strings.Compare(a, b)
+	})
+	return result
+}
+
+// prefix возвращает слова словаря, начинающиеся с prefix, по возрастанию.
+func (l *lookup) prefix(prefix string) []string {
+	prefix = l.index.opts.word(prefix)
+	start, _ := slices.BinarySearch(l.words, prefix)
+	end := start
+	for end < len(l.words) && strings.HasPrefix(l.words[end], prefix) {
+		end++
+	}
+	return slices.Clone(l.words[start:end])
+}
+
+// runCount — руна и число её повторений.
+type runCount struct {
+	r     rune
+	count int
+}
+
+// runLengths сворачивает отсортированные руны в пары (руна, число повторений).
+func runLengths(sorted []rune) []runCount {
+	var counts []runCount
+	for _, r := range sorted {
+		if n := len(counts); n > 0 && counts[n-1].r == r {
+			counts[n-1].count++
+			continue
+		}
+		counts = append(counts, runCount{r: r, count: 1})
+	}
+	return counts
+}
+
+// subsetsAtMost сообщает, что число непустых подмножеств букв не больше limit.
+func subsetsAtMost(counts []runCount, limit int) bool {
+	total := 1
+	for _, c := range counts {
+		total *= c.count + 1
+		if total-1 > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// forEachSubset вызывает fn для каждого непустого подмножества букв в виде отсортированной строки.
+func forEachSubset(counts []runCount, fn func(key string)) {
+	buf := make([]rune, 0, len(counts))
+	var walk func(i int)
+	walk = func(i int) {
+		if i == len(counts) {
+			if len(buf) > 0 {
+				fn(string(buf))
+			}
+			return
+		}
+		n := len(buf)
+		for k := 0; k <= counts[i].count; k++ {
+			walk(i + 1)
+			buf = append(buf, counts[i].r)
+		}
+		buf = buf[:n]
+	}
+	walk(0)
+}
+
+// containsAll сообщает, что отсортированные руны key входят в отсортированные руны letters
+// с учётом повторений.
+func containsAll(letters []rune, key string) bool {
+	i := 0
+	for _, r := range key {
+		for i < len(letters) && letters[i] < r {
+			i++
+		}
+		if i == len(letters) || letters[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
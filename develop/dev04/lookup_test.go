@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var dictionary = []string{"пятак", "пятка", "тяпка", "пята", "тяп", "как", "кат", "так", "акт", "кот", "ток", "а", "пятаки"}
+
+func newTestLookup(t *testing.T, words []string, opts Options) *lookup {
+	t.Helper()
+	index := newAnagramIndex(opts)
+	for _, word := range words {
+		index.add(word)
+	}
+	return newLookup(index)
+}
+
+func TestLookupAnagrams(t *testing.T) {
+	l := newTestLookup(t, dictionary, Options{})
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"Пятак", []string{"пятка", "тяпка"}},
+		{"катяп", []string{"пятак", "пятка", "тяпка"}}, // Слова нет в словаре
+		{"акт", []string{"кат", "так"}},
+		{"пятаки", nil},
+		{"слово", nil},
+	}
+	for _, tt := range tests {
+		if got := l.anagrams(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("anagrams(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestLookupSubAnagrams(t *testing.T) {
+	l := newTestLookup(t, dictionary, Options{})
+	tests := []struct {
+		letters string
+		want    []string
+	}{
+		{"пятак", []string{"пятак", "пятка", "тяпка", "пята", "акт", "кат", "так", "тяп", "а"}},
+		{"тка", []string{"акт", "кат", "так", "а"}},
+		{"кк", nil},
+		{"какао", []string{"как", "а"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := l.subAnagrams(tt.letters); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("subAnagrams(%q) = %q, want %q", tt.letters, got, tt.want)
+		}
+	}
+}
+
+// TestSubAnagramsStrategies проверяет, что перебор подмножеств букв и проверка каждой группы
+// дают одинаковый результат.
+func TestSubAnagramsStrategies(t *testing.T) {
+	l := newTestLookup(t, dictionary, Options{})
+	for _, letters := range []string{"пятак", "аакпттяя", "ктоакя", "я"} {
+		runes := []rune(normalizeWord(letters, Options{}))
+		var bySubsets, byGroups []string
+		forEachSubset(runLengths(runes), func(key string) {
+			bySubsets = append(bySubsets, l.index.groups[key]...)
+		})
+		for key, group := range l.index.groups {
+			if containsAll(runes, key) {
+				byGroups = append(byGroups, group...)
+			}
+		}
+		slices.Sort(bySubsets)
+		slices.Sort(byGroups)
+		if !slices.Equal(bySubsets, byGroups) {
+			t.Errorf("%q: subsets %q, groups %q", letters, bySubsets, byGroups)
+		}
+	}
+
+	if subsetsAtMost(runLengths([]rune("абвгд")), 30) {
+		t.Error("subsetsAtMost(абвгд, 30) = true, want false: 31 subsets")
+	}
+	if !subsetsAtMost(runLengths([]rune("абвгд")), 31) {
+		t.Error("subsetsAtMost(абвгд, 31) = false, want true")
+	}
+}
+
+func TestLookupPrefix(t *testing.T) {
+	l := newTestLookup(t, dictionary, Options{})
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"Пят", []string{"пята", "пятак", "пятаки", "пятка"}},
+		{"пятак", []string{"пятак", "пятаки"}},
+		{"я", []string{}},
+	}
+	for _, tt := range tests {
+		if got := l.prefix(tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("prefix(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestLookupOptions(t *testing.T) {
+	l := newTestLookup(t, []string{"сёла", "леса", "ле-са"}, Options{FoldYo: true, LettersOnly: true})
+	if got, want := l.anagrams("Села"), []string{"ле-са", "леса", "сёла"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("anagrams(Села) = %q, want %q", got, want)
+	}
+	if got, want := l.subAnagrams("сёлах"), []string{"ле-са", "леса", "сёла"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subAnagrams(сёлах) = %q, want %q", got, want)
+	}
+}
+
+func TestSaveLoadIndex(t *testing.T) {
+	opts := Options{Form: FormNFC, FoldYo: true}
+	index := newAnagramIndex(opts)
+	if err := index.readFrom(strings.NewReader(strings.Join(dictionary, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "index.gob")
+	if err := saveIndexFile(index, name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadIndexFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.opts != opts {
+		t.Errorf("loaded options = %+v, want %+v", loaded.opts, opts)
+	}
+	if !reflect.DeepEqual(loaded.sets(), index.sets()) {
+		t.Errorf("loaded sets = %v, want %v", loaded.sets(), index.sets())
+	}
+	if !reflect.DeepEqual(newLookup(loaded).words, newLookup(index).words) {
+		t.Error("loaded index has different words")
+	}
+
+	// Добавление в загруженный индекс учитывает уже известные слова.
+	loaded.add("ПЯТАК")
+	loaded.add("катяп")
+	if got, want := loaded.groups[normalizeWord("пятак", opts)], []string{"пятак", "пятка", "тяпка", "катяп"}; !slices.Equal(got, want) {
+		t.Errorf("group after add = %q, want %q", got, want)
+	}
+}
+
+func TestLoadIndexErrors(t *testing.T) {
+	if _, err := loadIndex(strings.NewReader("not an index")); err == nil {
+		t.Error("loadIndex(garbage): want error")
+	}
+
+	var buf bytes.Buffer
+	index := newAnagramIndex(Options{})
+	index.add("кот")
+	if err := index.save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadIndex(bytes.NewReader(buf.Bytes()[:buf.Len()/2])); err == nil {
+		t.Error("loadIndex(truncated): want error")
+	}
+}
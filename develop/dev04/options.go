@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Form — форма нормализации Unicode, применяемая к словам.
+type Form int
+
+// Поддерживаемые формы нормализации.
+const (
+	FormNone Form = iota // Без нормализации
+	FormNFC              // Каноническая композиция: «е» + U+0308 -> «ё»
+	FormNFD              // Каноническая декомпозиция: «ё» -> «е» + U+0308
+)
+
+// forms сопоставляет имена форм из командной строки значениям Form.
+var forms = map[string]Form{
+	"none": FormNone,
+	"nfc":  FormNFC,
+	"nfd":  FormNFD,
+}
+
+// parseForm разбирает имя формы нормализации.
+func parseForm(name string) (Form, error) {
+	form, ok := forms[strings.ToLower(name)]
+	if !ok {
+		return FormNone, fmt.Errorf("unknown normalization form %q", name)
+	}
+	return form, nil
+}
+
+// Options задаёт правила приведения слов к общему виду при поиске анаграмм.
+// Нулевое значение соответствует исходному поведению: слова только приводятся к нижнему регистру.
+type Options struct {
+	Form        Form // Нормализация Unicode слов
+	FoldCase    bool // Полное свёртывание регистра Unicode (ß -> ss, ς -> σ) вместо strings.ToLower
+	FoldYo      bool // Считать «ё» буквой «е»
+	LettersOnly bool // Игнорировать не-буквы: дефисы, апострофы, цифры, комбинируемые диакритические знаки
+}
+
+// word приводит слово к виду, в котором оно хранится и выводится:
+// нижний регистр (или свёрнутый регистр) и выбранная форма нормализации.
+func (o Options) word(word string) string {
+	if o.FoldCase {
+		word = cases.Fold().String(word) // Caser не потокобезопасен, поэтому создаётся на каждый вызов
+	} else {
+		word = strings.ToLower(word)
+	}
+
+	switch o.Form {
+	case FormNFC:
+		word = norm.NFC.String(word)
+	case FormNFD:
+		word = norm.NFD.String(word)
+	}
+	return word
+}
+
+// yoReplacer заменяет «ё» на «е» в обоих регистрах.
+var yoReplacer = strings.NewReplacer("ё", "е", "Ё", "Е")
+
+// keep сообщает, участвует ли руна в сравнении слов.
+func (o Options) keep(r rune) bool {
+	return !o.LettersOnly || unicode.IsLetter(r)
+}
+
+// runes возвращает руны слова, по которым сравниваются анаграммы.
+func (o Options) runes(word string) []rune {
+	if o.FoldYo {
+		// «ё» во входных данных может быть разложена на «е» + U+0308, поэтому сначала собираем её.
+		word = yoReplacer.Replace(norm.NFC.String(word))
+		if o.Form == FormNFD {
+			word = norm.NFD.String(word)
+		}
+	}
+
+	runes := make([]rune, 0, len(word))
+	for _, r := range word {
+		if o.keep(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
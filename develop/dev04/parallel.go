@@ -0,0 +1,143 @@
+package main
+
+import (
+	"cmp"
+	"hash/maphash"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// findAnagramsParallel — параллельный вариант findAnagrams; workers <= 0 означает GOMAXPROCS.
+func findAnagramsParallel(words []string, opts Options, workers int) *map[string][]string {
+	result := make(map[string][]string)
+	for _, set := range findAnagramSetsParallel(words, opts, workers) {
+		result[set.Key] = set.Words
+	}
+	return &result
+}
+
+// findAnagramSetsParallel — параллельный вариант findAnagramSets с тем же результатом.
+//
+// Словарь делится на workers непрерывных частей, которые нормализуются параллельно.
+// Каждое слово направляется в шард по хешу нормализованной формы, поэтому вся группа
+// и все повторы слова попадают в один шард. Шарды группируют слова параллельно,
+// просматривая части по порядку, и запоминают позицию первого слова каждой группы,
+// по которой затем восстанавливается порядок словаря.
+func findAnagramSetsParallel(words []string, opts Options, workers int) []AnagramSet {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers == 1 || len(words) < 2 {
+		return findAnagramSets(words, opts)
+	}
+
+	entries := make([]entry, len(words))
+	buckets := make([][][]int, workers) // buckets[w][s] — позиции слов части w, попавших в шард s
+	seed := maphash.MakeSeed()
+	chunk := (len(words) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for w := range buckets {
+		buckets[w] = make([][]int, workers)
+		lo, hi := min(w*chunk, len(words)), min((w+1)*chunk, len(words))
+		wg.Add(1)
+		go func(w, lo, hi int) {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				word := opts.word(words[i])
+				key := normalizeWord(word, opts)
+				entries[i] = entry{word: word, key: key}
+				s := maphash.String(seed, key) % uint64(workers)
+				buckets[w][s] = append(buckets[w][s], i)
+			}
+		}(w, lo, hi)
+	}
+	wg.Wait()
+
+	shards := make([]*shard, workers)
+	for s := range shards {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			sh := newShard()
+			for w := range buckets { // Части по порядку, позиции внутри части по возрастанию
+				for _, i := range buckets[w][s] {
+					sh.add(i, entries[i])
+				}
+			}
+			shards[s] = sh
+		}(s)
+	}
+	wg.Wait()
+
+	var groups []shardGroup
+	for _, sh := range shards {
+		for _, group := range sh.groups {
+			if len(group.words) > 1 { // Исключение множеств с одним элементом
+				groups = append(groups, group)
+			}
+		}
+	}
+	slices.SortFunc(groups, func(a, b shardGroup) int {
+		return cmp.Compare(a.first, b.first)
+	})
+
+	sets := make([]AnagramSet, 0, len(groups))
+	for _, group := range groups {
+		key := group.words[0]
+		slices.Sort(group.words) // Группа принадлежит только этому вызову, копия не нужна
+		sets = append(sets, AnagramSet{Key: key, Words: group.words})
+	}
+	if len(sets) == 0 {
+		return nil // Как и findAnagramSets
+	}
+	return sets
+}
+
+// entry — слово словаря в виде для хранения и его нормализованная форма.
+type entry struct {
+	word string
+	key  string
+}
+
+// shardGroup — группа слов шарда и позиция её первого слова в словаре.
+type shardGroup struct {
+	first int
+	words []string
+}
+
+// shard группирует слова, нормализованные формы которых попали в него по хешу.
+type shard struct {
+	index  map[string]int      // Нормализованная форма -> номер группы в groups
+	groups []shardGroup        // Группы в порядке появления
+	seen   map[string]struct{} // Уже добавленные слова
+}
+
+// newShard создаёт пустой шард.
+func newShard() *shard {
+	return &shard{
+		index: make(map[string]int),
+		seen:  make(map[string]struct{}),
+	}
+}
+
+// add добавляет слово, стоящее в словаре на позиции pos; повторы слова
+// и слова с пустой нормализованной формой пропускаются, как в anagramIndex.add.
+func (sh *shard) add(pos int, e entry) {
+	if e.key == "" {
+		return
+	}
+	if _, exists := sh.seen[e.word]; exists {
+		return
+	}
+	sh.seen[e.word] = struct{}{}
+
+	n, exists := sh.index[e.key]
+	if !exists {
+		n = len(sh.groups)
+		sh.index[e.key] = n
+		sh.groups = append(sh.groups, shardGroup{first: pos})
+	}
+	sh.groups[n].words = append(sh.groups[n].words, e.word)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// corpus генерирует словарь из n слов: перестановки букв ограниченного набора основ,
+// часть слов в верхнем регистре и с «ё», поэтому в словаре много анаграмм и повторов.
+func corpus(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	alphabet := []rune("абвгдеёжзийклмнопрстуфхцчшщъыьэюя")
+	bases := make([][]rune, n/8+1)
+	for i := range bases {
+		base := make([]rune, 3+rng.Intn(8))
+		for j := range base {
+			base[j] = alphabet[rng.Intn(len(alphabet))]
+		}
+		bases[i] = base
+	}
+
+	words := make([]string, n)
+	for i := range words {
+		word := append([]rune(nil), bases[rng.Intn(len(bases))]...)
+		rng.Shuffle(len(word), func(a, b int) {
+			word[a], word[b] = word[b], word[a]
+		})
+		words[i] = string(word)
+		if rng.Intn(10) == 0 {
+			words[i] = strings.ToUpper(words[i])
+		}
+	}
+	return words
+}
+
+func TestFindAnagramSetsParallelMatchesSequential(t *testing.T) {
+	words := corpus(20000, 1)
+	for _, opts := range []Options{{}, {FoldYo: true}, {Form: FormNFD, LettersOnly: true, FoldCase: true}} {
+		want := findAnagramSets(words, opts)
+		if len(want) == 0 {
+			t.Fatal("corpus has no anagrams")
+		}
+		for _, workers := range []int{0, 1, 2, 3, 8, 64} {
+			if got := findAnagramSetsParallel(words, opts, workers); !reflect.DeepEqual(got, want) {
+				t.Errorf("findAnagramSetsParallel(%+v, workers=%d) differs from findAnagramSets", opts, workers)
+			}
+		}
+	}
+}
+
+func TestFindAnagramsParallel(t *testing.T) {
+	tests := [][]string{
+		nil,
+		{"кот"},
+		{"кот", "ток"},
+		{"тяпка", "пятак", "столик", "листок", "пятка", "ПЯТАК", "п"},
+	}
+	for _, words := range tests {
+		want := findAnagrams(words, Options{})
+		// Рабочих больше, чем слов: часть частей словаря пуста.
+		if got := findAnagramsParallel(words, Options{}, 8); !reflect.DeepEqual(*got, *want) {
+			t.Errorf("findAnagramsParallel(%q) = %v, want %v", words, *got, *want)
+		}
+	}
+}
+
+func TestFindAnagramSetsParallelSkipsEmptyKeys(t *testing.T) {
+	words := []string{"123", "кот", "45", "ток", "--", "-"}
+	want := []AnagramSet{{Key: "кот", Words: []string{"кот", "ток"}}}
+	for _, workers := range []int{2, 8} {
+		if got := findAnagramSetsParallel(words, Options{LettersOnly: true}, workers); !reflect.DeepEqual(got, want) {
+			t.Errorf("findAnagramSetsParallel(workers=%d) = %q, want %q", workers, got, want)
+		}
+	}
+}
+
+// TestFindAnagramSetsParallelConcurrent запускает несколько параллельных поисков одновременно
+// по общему словарю; вместе с -race проверяет отсутствие гонок.
+func TestFindAnagramSetsParallelConcurrent(t *testing.T) {
+	words := corpus(5000, 2)
+	want := findAnagramSets(words, Options{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(workers int) {
+			defer wg.Done()
+			if got := findAnagramSetsParallel(words, Options{}, workers); !reflect.DeepEqual(got, want) {
+				t.Errorf("concurrent findAnagramSetsParallel(workers=%d) differs", workers)
+			}
+		}(i + 2)
+	}
+	wg.Wait()
+}
+
+var (
+	benchCorpusOnce sync.Once
+	benchCorpus     []string
+)
+
+// millionWords возвращает словарь из миллиона слов, общий для всех бенчмарков.
+func millionWords() []string {
+	benchCorpusOnce.Do(func() {
+		benchCorpus = corpus(1_000_000, 42)
+	})
+	return benchCorpus
+}
+
+func BenchmarkFindAnagramSets(b *testing.B) {
+	words := millionWords()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		findAnagramSets(words, Options{})
+	}
+}
+
+func BenchmarkFindAnagramSetsParallel(b *testing.B) {
+	words := millionWords()
+	for _, workers := range []int{2, 4, 8, 0} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				findAnagramSetsParallel(words, Options{}, workers)
+			}
+		})
+	}
+}
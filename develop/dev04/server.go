@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Таймауты HTTP-сервера.
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 10 * time.Second
+)
+
+// lookupResponse — ответ на запрос к словарю.
+type lookupResponse struct {
+	Query string   `json:"query"`
+	Words []string `json:"words"`
+}
+
+// errorResponse — ответ с ошибкой запроса.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// newHandler возвращает HTTP JSON API поверх l:
+//
+//	GET /anagrams?word=пятак     — анаграммы слова
+//	GET /subanagrams?letters=... — слова, составленные из части букв
+//	GET /prefix?q=пят            — слова с заданным префиксом
+//
+// Все запросы принимают необязательный параметр limit — максимальное число слов в ответе.
+func newHandler(l *lookup) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /anagrams", queryHandler("word", l.anagrams))
+	mux.Handle("GET /subanagrams", queryHandler("letters", l.subAnagrams))
+	mux.Handle("GET /prefix", queryHandler("q", l.prefix))
+	return mux
+}
+
+// queryHandler отвечает результатом find для параметра запроса param.
+func queryHandler(param string, find func(string) []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		value := query.Get(param)
+		if value == "" {
+			writeJSONResponse(w, http.StatusBadRequest, errorResponse{fmt.Sprintf("missing parameter %q", param)})
+			return
+		}
+		limit := 0
+		if s := query.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				writeJSONResponse(w, http.StatusBadRequest, errorResponse{fmt.Sprintf("invalid limit %q", s)})
+				return
+			}
+			limit = n
+		}
+
+		words := find(value)
+		if limit > 0 && len(words) > limit {
+			words = words[:limit]
+		}
+		if words == nil {
+			words = []string{} // Пустой результат выводится как [], а не null.
+		}
+		writeJSONResponse(w, http.StatusOK, lookupResponse{Query: value, Words: words})
+	})
+}
+
+// writeJSONResponse записывает ответ v в формате JSON с кодом status.
+func writeJSONResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// serve обслуживает HTTP-запросы на addr до отмены ctx, после чего корректно завершает сервер.
+func serve(ctx context.Context, addr string, handler http.Handler, stderr io.Writer) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: readHeaderTimeout}
+	fmt.Fprintln(stderr, "listening on", ln.Addr())
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
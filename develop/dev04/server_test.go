@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(newHandler(newTestLookup(t, dictionary, Options{})))
+	defer srv.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/anagrams?word=" + url.QueryEscape("пятак"), http.StatusOK, `{"query":"пятак","words":["пятка","тяпка"]}`},
+		{"/anagrams?word=" + url.QueryEscape("слово"), http.StatusOK, `{"query":"слово","words":[]}`},
+		{"/subanagrams?limit=3&letters=" + url.QueryEscape("тка"), http.StatusOK, `{"query":"тка","words":["акт","кат","так"]}`},
+		{"/prefix?q=" + url.QueryEscape("пята"), http.StatusOK, `{"query":"пята","words":["пята","пятак","пятаки"]}`},
+		{"/anagrams", http.StatusBadRequest, `{"error":"missing parameter \"word\""}`},
+		{"/prefix?q=a&limit=-1", http.StatusBadRequest, `{"error":"invalid limit \"-1\""}`},
+		{"/unknown", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		if tt.want != "" && strings.TrimSpace(string(body)) != tt.want {
+			t.Errorf("GET %s = %s, want %s", tt.path, body, tt.want)
+		}
+	}
+
+	resp, err := http.Post(srv.URL+"/anagrams?word=a", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /anagrams: status %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- serve(ctx, "127.0.0.1:0", newHandler(newTestLookup(t, dictionary, Options{})), pw)
+	}()
+
+	// Адрес сервера выводится в первой строке.
+	line := make([]byte, 64)
+	n, err := pr.Read(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(io.Discard, pr)
+	addr := strings.TrimSpace(strings.TrimPrefix(string(line[:n]), "listening on"))
+
+	resp, err := http.Get("http://" + addr + "/anagrams?word=" + url.QueryEscape("тяпка"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got lookupResponse
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil || !reflect.DeepEqual(got.Words, []string{"пятак", "пятка"}) {
+		t.Errorf("GET /anagrams = %+v, %v", got, err)
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("serve() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not stop after cancel")
+	}
+}
+
+func TestRunSaveLoad(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "index.gob")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-yo", "-save", name}, strings.NewReader("сёла\nлеса\nкот\n"), &stdout, &stderr)
+	if code != exitOK || stdout.Len() != 0 {
+		t.Fatalf("run(-save) = %d, %q, stderr %q", code, stdout.String(), stderr.String())
+	}
+
+	code = run([]string{"-load", name, "-format", "tsv"}, strings.NewReader(""), &stdout, &stderr)
+	if want := "сёла\tлеса\tсёла\n"; code != exitOK || stdout.String() != want {
+		t.Errorf("run(-load) = %d, %q, stderr %q; want %q", code, stdout.String(), stderr.String(), want)
+	}
+
+	if code := run([]string{"-load", name, "dict.txt"}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Errorf("run(-load with files) = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"-load", filepath.Join(t.TempDir(), "missing")}, strings.NewReader(""), &stdout, &stderr); code != exitIO {
+		t.Errorf("run(-load missing) = %d, want %d", code, exitIO)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+)
+
+// indexVersion — версия формата сохранённого индекса.
+const indexVersion = 1
+
+// indexSnapshot — сохраняемое на диск представление индекса.
+type indexSnapshot struct {
+	Version int
+	Options Options
+	Keys    []string   // Нормализованные формы в порядке появления
+	Groups  [][]string // Слова групп в порядке появления, параллельно Keys
+}
+
+// save записывает индекс в w в формате gob.
+func (idx *anagramIndex) save(w io.Writer) error {
+	snapshot := indexSnapshot{
+		Version: indexVersion,
+		Options: idx.opts,
+		Keys:    idx.order,
+		Groups:  make([][]string, len(idx.order)),
+	}
+	for i, key := range idx.order {
+		snapshot.Groups[i] = idx.groups[key]
+	}
+	return gob.NewEncoder(w).Encode(snapshot)
+}
+
+// loadIndex читает индекс, записанный методом save.
+func loadIndex(r io.Reader) (*anagramIndex, error) {
+	var snapshot indexSnapshot
+	if err := gob.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("decode index: %w", err)
+	}
+	if snapshot.Version != indexVersion {
+		return nil, fmt.Errorf("unsupported index version %d", snapshot.Version)
+	}
+	if len(snapshot.Keys) != len(snapshot.Groups) {
+		return nil, fmt.Errorf("corrupted index: %d keys, %d groups", len(snapshot.Keys), len(snapshot.Groups))
+	}
+
+	idx := newAnagramIndex(snapshot.Options)
+	idx.order = snapshot.Keys
+	for i, key := range snapshot.Keys {
+		idx.groups[key] = snapshot.Groups[i]
+		for _, word := range snapshot.Groups[i] {
+			idx.seen[word] = struct{}{}
+		}
+	}
+	return idx, nil
+}
+
+// saveIndexFile сохраняет индекс в файл name.
+func saveIndexFile(idx *anagramIndex, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = idx.save(w)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// loadIndexFile загружает индекс из файла name.
+func loadIndexFile(name string) (*anagramIndex, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return loadIndex(bufio.NewReader(f))
+}
@@ -1,9 +1,8 @@
 package main
 
 import (
-	"fmt"
-	"sort"
-	"strings"
+	"os"
+	"slices"
 )
 
 /*
@@ -25,51 +24,34 @@ import (
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
-// normalizeWord нормализует слово, приводя его к нижнему регистру и сортируя его буквы.
+// normalizeWord нормализует слово по правилам opts, приводя его к нижнему регистру и сортируя его буквы.
 // Это помогает идентифицировать анаграммы, приводя все слова к единому представлению.
-func normalizeWord(word string) string {
-	word = strings.ToLower(word) // Приведение слова к нижнему регистру
-	runes := []rune(word)        // Преобразование строки в слайс рун для корректной сортировки Unicode символов
-	sort.Slice(runes, func(i, j int) bool {
-		return runes[i] < runes[j] // Сортировка рун в алфавитном порядке
-	})
-	return string(runes) // Преобразование отсортированных рун обратно в строку
+func normalizeWord(word string, opts Options) string {
+	runes := opts.runes(opts.word(word)) // Руны, чтобы корректно сортировать символы Unicode
+	slices.Sort(runes)                   // Обобщённая сортировка без замыкания на каждое сравнение
+	return string(runes)
 }
 
 // findAnagrams находит все множества анаграмм в заданном словаре.
-func findAnagrams(words []string) *map[string][]string {
-	anagrams := make(map[string][]string)    // Мапа для хранения групп анаграмм
-	uniqueWords := make(map[string]struct{}) // Мапа для хранения уникальных слов
-
-	// Группируем слова по их нормализованной форме.
-	for _, word := range words {
-		loweredWord := strings.ToLower(word) // Приведение слова к нижнему регистру
-		if _, exists := uniqueWords[loweredWord]; exists {
-			continue // Пропуск дублирующихся слов
-		}
-		uniqueWords[loweredWord] = struct{}{} // Добавление уникального слова в мапу
-
-		normalized := normalizeWord(loweredWord)                         // Нормализация слова
-		anagrams[normalized] = append(anagrams[normalized], loweredWord) // Добавление слова в группу анаграмм
-	}
-
-	// Формируем результат, исключая множества с одним элементом и сортируя слова в каждом множестве.
+// Ключ мапы — первое встретившееся в словаре слово множества.
+func findAnagrams(words []string, opts Options) *map[string][]string {
 	result := make(map[string][]string)
-	for _, group := range anagrams {
-		if len(group) > 1 { // Исключение множеств с одним элементом
-			sort.Strings(group)      // Сортировка слов в множестве по алфавиту
-			result[group[0]] = group // Ключом является первое встретившееся слово в множестве
-		}
+	for _, set := range findAnagramSets(words, opts) {
+		result[set.Key] = set.Words
 	}
-
 	return &result // Возвращаем ссылку на результирующую мапу
 }
 
-func main() {
-	// Массив слов для поиска анаграмм
-	words := []string{"пятак", "пятка", "тяпка", "листок", "слиток", "столик", "столик", "п", "п"}
-	anagrams := findAnagrams(words)     // Вызов функции поиска анаграмм
-	for key, group := range *anagrams { // Вывод результатов
-		fmt.Printf("%s: %v\n", key, group)
+// findAnagramSets находит все множества анаграмм в заданном словаре и возвращает их
+// в порядке словаря: множества упорядочены по первому появлению ключа.
+func findAnagramSets(words []string, opts Options) []AnagramSet {
+	index := newAnagramIndex(opts)
+	for _, word := range words {
+		index.add(word)
 	}
+	return index.sets()
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
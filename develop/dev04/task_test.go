@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWord(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"", ""},
+		{"a", "a"},
+		{"пятак", "акптя"},
+		{"Тяпка", "акптя"},
+		{"ЁЖИК", "жикё"},
+		{"cab", "abc"},
+	}
+	for _, tt := range tests {
+		if got := normalizeWord(tt.word, Options{}); got != tt.want {
+			t.Errorf("normalizeWord(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestFindAnagrams(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		want  map[string][]string
+	}{
+		{
+			name:  "пример из условия",
+			words: []string{"пятак", "пятка", "тяпка", "листок", "слиток", "столик", "столик", "п", "п"},
+			want: map[string][]string{
+				"пятак":  {"пятак", "пятка", "тяпка"},
+				"листок": {"листок", "слиток", "столик"},
+			},
+		},
+		{
+			name:  "ключ — первое встретившееся слово, а не наименьшее",
+			words: []string{"тяпка", "пятак", "столик", "листок", "пятка"},
+			want: map[string][]string{
+				"тяпка":  {"пятак", "пятка", "тяпка"},
+				"столик": {"листок", "столик"},
+			},
+		},
+		{
+			name:  "регистр и повторы",
+			words: []string{"Пятак", "ПЯТКА", "пятак", "пятка"},
+			want:  map[string][]string{"пятак": {"пятак", "пятка"}},
+		},
+		{
+			name:  "множества из одного слова не попадают в результат",
+			words: []string{"кот", "пёс", "кот"},
+			want:  map[string][]string{},
+		},
+		{
+			name:  "пустой словарь",
+			words: nil,
+			want:  map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		got := findAnagrams(tt.words, Options{})
+		if got == nil {
+			t.Fatalf("%s: findAnagrams() = nil", tt.name)
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: findAnagrams() = %v, want %v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestFindAnagramSetsOrder(t *testing.T) {
+	words := []string{"листок", "кот", "тяпка", "ток", "слиток", "пятак", "кто", "столик"}
+	want := []AnagramSet{
+		{Key: "листок", Words: []string{"листок", "слиток", "столик"}},
+		{Key: "кот", Words: []string{"кот", "кто", "ток"}},
+		{Key: "тяпка", Words: []string{"пятак", "тяпка"}},
+	}
+	for i := 0; i < 10; i++ { // Порядок не должен зависеть от порядка обхода мапы.
+		if got := findAnagramSets(words, Options{}); !reflect.DeepEqual(got, want) {
+			t.Fatalf("findAnagramSets() = %v, want %v", got, want)
+		}
+	}
+
+	if got := findAnagramSets([]string{"один"}, Options{}); len(got) != 0 {
+		t.Errorf("findAnagramSets(один) = %v, want empty", got)
+	}
+}
+
+func TestFindAnagramSetsOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		words []string
+		want  []AnagramSet
+	}{
+		{
+			name:  "ё без FoldYo",
+			words: []string{"сёла", "леса"},
+		},
+		{
+			name:  "ё как е",
+			opts:  Options{FoldYo: true},
+			words: []string{"сёла", "леса"},
+			want:  []AnagramSet{{Key: "сёла", Words: []string{"леса", "сёла"}}},
+		},
+		{
+			name:  "разложенная ё как е",
+			opts:  Options{FoldYo: true},
+			words: []string{"се\u0308ла", "леса"},
+			want:  []AnagramSet{{Key: "се\u0308ла", Words: []string{"леса", "се\u0308ла"}}},
+		},
+		{
+			name:  "разные формы без нормализации",
+			words: []string{"се\u0308ла", "ласё"},
+		},
+		{
+			name:  "NFC объединяет формы и повторы",
+			opts:  Options{Form: FormNFC},
+			words: []string{"се\u0308ла", "ласё", "сёла"},
+			want:  []AnagramSet{{Key: "сёла", Words: []string{"ласё", "сёла"}}},
+		},
+		{
+			name:  "NFD и только буквы отбрасывают диакритику",
+			opts:  Options{Form: FormNFD, LettersOnly: true},
+			words: []string{"Café", "face"},
+			want:  []AnagramSet{{Key: "cafe\u0301", Words: []string{"cafe\u0301", "face"}}},
+		},
+		{
+			name:  "дефисы и апострофы",
+			opts:  Options{LettersOnly: true},
+			words: []string{"ток", "к-о-т", "о'кт", "кот1"},
+			want:  []AnagramSet{{Key: "ток", Words: []string{"к-о-т", "кот1", "о'кт", "ток"}}},
+		},
+		{
+			name:  "слова без букв не образуют множество",
+			opts:  Options{LettersOnly: true},
+			words: []string{"123", "45", "--"},
+		},
+		{
+			name:  "нижний регистр не раскрывает ß",
+			words: []string{"Maß", "sams"},
+		},
+		{
+			name:  "свёртывание регистра",
+			opts:  Options{FoldCase: true},
+			words: []string{"Maß", "SAMS", "mass"},
+			want:  []AnagramSet{{Key: "mass", Words: []string{"mass", "sams"}}},
+		},
+	}
+	for _, tt := range tests {
+		if got := findAnagramSets(tt.words, tt.opts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: findAnagramSets(%q, %+v) = %q, want %q", tt.name, tt.words, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	for name, want := range map[string]Form{"none": FormNone, "nfc": FormNFC, "NFD": FormNFD} {
+		if got, err := parseForm(name); err != nil || got != want {
+			t.Errorf("parseForm(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := parseForm("nfkc"); err == nil {
+		t.Error("parseForm(nfkc): want error")
+	}
+}
+
+func TestIndexReadFrom(t *testing.T) {
+	index := newAnagramIndex(Options{})
+	input := "  Пятак \r\n\n\tтяпка\nкот\n"
+	if err := index.readFrom(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	want := []AnagramSet{{Key: "пятак", Words: []string{"пятак", "тяпка"}}}
+	if got := index.sets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sets() = %v, want %v", got, want)
+	}
+
+	// Слова без букв не попадают в индекс, а значит, и в сохранённый -save файл.
+	index = newAnagramIndex(Options{LettersOnly: true})
+	if err := index.readFrom(strings.NewReader("123\n45\n--\n-\nкот\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := index.groups[""]; exists || len(index.order) != 1 {
+		t.Errorf("index with LettersOnly has groups %q, want only the group of \"кот\"", index.order)
+	}
+}
+
+func TestRunFormats(t *testing.T) {
+	input := "тяпка\nПятка\n\n  пятак \nлисток\nслиток\nстолик\nстолик\nп\n"
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `[{"key":"тяпка","words":["пятак","пятка","тяпка"]},{"key":"листок","words":["листок","слиток","столик"]}]` + "\n"},
+		{"tsv", "тяпка\tпятак\tпятка\tтяпка\nлисток\tлисток\tслиток\tстолик\n"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-format", tt.format}, strings.NewReader(input), &stdout, &stderr)
+		if code != exitOK || stdout.String() != tt.want {
+			t.Errorf("run(-format %s) = %d, %q, stderr %q; want %q", tt.format, code, stdout.String(), stderr.String(), tt.want)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(nil, strings.NewReader("кот\n"), &stdout, &stderr); code != exitOK || stdout.String() != "[]\n" {
+		t.Errorf("run() without anagrams = %d, %q, want []", code, stdout.String())
+	}
+
+	stdout.Reset()
+	code := run([]string{"-format", "tsv", "-norm", "nfc", "-yo", "-letters"}, strings.NewReader("Се\u0308ла\nл-еса\nсёла\n"), &stdout, &stderr)
+	if want := "сёла\tл-еса\tсёла\n"; code != exitOK || stdout.String() != want {
+		t.Errorf("run(-norm nfc -yo -letters) = %d, %q, stderr %q; want %q", code, stdout.String(), stderr.String(), want)
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("столик\nпятак\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("листок\nтяпка\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-format", "tsv", first, "-", second}, strings.NewReader("слиток\n"), &stdout, &stderr)
+	want := "столик\tлисток\tслиток\tстолик\nпятак\tпятак\tтяпка\n"
+	if code != exitOK || stdout.String() != want {
+		t.Errorf("run(files) = %d, %q, stderr %q; want %q", code, stdout.String(), stderr.String(), want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-format", "xml"}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Errorf("run(-format xml) = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"-norm", "nfkc"}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Errorf("run(-norm nfkc) = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"-unknown"}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Errorf("run(-unknown) = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"no-such-file"}, strings.NewReader(""), &stdout, &stderr); code != exitIO {
+		t.Errorf("run(no-such-file) = %d, want %d", code, exitIO)
+	}
+}
+
+// normalizeWordSortSlice — прежняя реализация normalizeWord через sort.Slice, для сравнения в бенчмарках.
+func normalizeWordSortSlice(word string) string {
+	runes := []rune(strings.ToLower(word))
+	sort.Slice(runes, func(i, j int) bool {
+		return runes[i] < runes[j]
+	})
+	return string(runes)
+}
+
+var benchWords = []string{"пятак", "Листок", "достопримечательность", "anagram", "a"}
+
+func TestNormalizeWordMatchesSortSlice(t *testing.T) {
+	for _, word := range append(benchWords, "", "ЁЖИК", "ab́c") {
+		if got, want := normalizeWord(word, Options{}), normalizeWordSortSlice(word); got != want {
+			t.Errorf("normalizeWord(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func BenchmarkNormalizeWord(b *testing.B) {
+	for _, word := range benchWords {
+		b.Run(word, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				normalizeWord(word, Options{})
+			}
+		})
+	}
+}
+
+func BenchmarkNormalizeWordSortSlice(b *testing.B) {
+	for _, word := range benchWords {
+		b.Run(word, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				normalizeWordSortSlice(word)
+			}
+		})
+	}
+}
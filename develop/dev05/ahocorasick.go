@@ -0,0 +1,261 @@
+package main
+
+import (
+	"slices"
+	"unicode"
+	"unicode/utf8"
+)
+
+// acEdge — переход автомата по байту.
+type acEdge struct {
+	b  byte
+	to int32
+}
+
+// acState — состояние автомата Ахо — Корасик.
+type acState struct {
+	edges []acEdge // Переходы бора, отсортированные по байту
+	fail  int32    // Состояние для самого длинного собственного суффикса
+	out   int32    // Номер шаблона, заканчивающегося в состоянии, или -1
+	dict  int32    // Ближайшее по цепочке fail состояние с шаблоном или -1
+}
+
+// ahoCorasick ищет в строке сразу все фиксированные шаблоны за один проход:
+// время поиска не зависит от числа шаблонов, в отличие от альтернативы в регулярном выражении.
+type ahoCorasick struct {
+	states     []acState
+	root       [256]int32 // Переходы из корня по всем байтам: в корне автомат проводит большую часть времени
+	lengths    []int      // Длина шаблонов в байтах, а с ignoreCase — в рунах, для поиска начала вхождения
+	ignoreCase bool
+	empty      bool // Среди шаблонов есть пустой: совпадает любая строка
+}
+
+// newAhoCorasick строит автомат по шаблонам. С ignoreCase шаблоны и строки
+// сравниваются после свёртывания регистра каждой руны (simple case folding).
+func newAhoCorasick(patterns []string, ignoreCase bool) *ahoCorasick {
+	ac := &ahoCorasick{
+		states:     []acState{{fail: 0, out: -1, dict: -1}},
+		lengths:    make([]int, len(patterns)),
+		ignoreCase: ignoreCase,
+	}
+
+	// Бор из шаблонов.
+	var buf []byte
+	for i, pattern := range patterns {
+		ac.lengths[i] = len(pattern)
+		if ignoreCase {
+			ac.lengths[i] = utf8.RuneCountInString(pattern)
+		}
+		if pattern == "" {
+			ac.empty = true
+			continue
+		}
+		buf = ac.fold(buf[:0], []byte(pattern))
+		state := int32(0)
+		for _, b := range buf {
+			next := ac.child(state, b)
+			if next < 0 {
+				next = int32(len(ac.states))
+				ac.states = append(ac.states, acState{out: -1, dict: -1})
+				edges := ac.states[state].edges
+				pos, _ := slices.BinarySearchFunc(edges, b, func(e acEdge, b byte) int { return int(e.b) - int(b) })
+				ac.states[state].edges = slices.Insert(edges, pos, acEdge{b: b, to: next})
+			}
+			state = next
+		}
+		if ac.states[state].out < 0 { // Из повторяющихся шаблонов запоминается первый
+			ac.states[state].out = int32(i)
+		}
+	}
+
+	for b := range ac.root {
+		ac.root[b] = max(ac.child(0, byte(b)), 0)
+	}
+
+	// Ссылки fail и dict обходом в ширину.
+	queue := []int32{0}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for _, e := range ac.states[state].edges {
+			child := &ac.states[e.to]
+			if state != 0 {
+				child.fail = ac.step(ac.states[state].fail, e.b)
+				if fail := ac.states[child.fail]; fail.out >= 0 {
+					child.dict = child.fail
+				} else {
+					child.dict = fail.dict
+				}
+			}
+			queue = append(queue, e.to)
+		}
+	}
+	return ac
+}
+
+// child возвращает переход бора из state по байту b или -1.
+func (ac *ahoCorasick) child(state int32, b byte) int32 {
+	edges := ac.states[state].edges
+	if pos, ok := slices.BinarySearchFunc(edges, b, func(e acEdge, b byte) int { return int(e.b) - int(b) }); ok {
+		return edges[pos].to
+	}
+	return -1
+}
+
+// step выполняет переход автомата из state по байту b с учётом ссылок fail.
+func (ac *ahoCorasick) step(state int32, b byte) int32 {
+	for {
+		if state == 0 {
+			return ac.root[b]
+		}
+		if next := ac.child(state, b); next >= 0 {
+			return next
+		}
+		state = ac.states[state].fail
+	}
+}
+
+// Match сообщает, содержит ли строка хотя бы один шаблон.
+func (ac *ahoCorasick) Match(line []byte) bool {
+	if ac.empty {
+		return true
+	}
+	found := false
+	ac.scan(line, func(int, int, int) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// FindAllSubmatchIndex возвращает не больше n (все при n < 0) непересекающихся вхождений,
+// как grep -o: самое левое, из начинающихся в одном месте — самое длинное, затем
+// следующее после его конца. Пустые вхождения не возвращаются.
+func (ac *ahoCorasick) FindAllSubmatchIndex(line []byte, n int) [][]int {
+	return ac.findAll(line, n, nil)
+}
+
+// findAll выбирает вхождения, как FindAllSubmatchIndex, среди отобранных keep;
+// keep == nil отбирает все.
+func (ac *ahoCorasick) findAll(line []byte, n int, keep func(start, end int) bool) [][]int {
+	var found [][]int
+	ac.scan(line, func(start, end, _ int) bool {
+		if keep == nil || keep(start, end) {
+			found = append(found, []int{start, end})
+		}
+		return true
+	})
+	slices.SortFunc(found, func(a, b []int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return b[1] - a[1]
+	})
+
+	all := found[:0]
+	for _, loc := range found {
+		if n >= 0 && len(all) == n {
+			break
+		}
+		if len(all) == 0 || loc[0] >= all[len(all)-1][1] {
+			all = append(all, loc)
+		}
+	}
+	return all
+}
+
+// scan вызывает fn для каждого вхождения шаблона в порядке конца вхождения:
+// start и end — границы вхождения в line в байтах, pattern — номер шаблона.
+// Поиск прекращается, если fn возвращает false. Пустые шаблоны не сообщаются.
+func (ac *ahoCorasick) scan(line []byte, fn func(start, end, pattern int) bool) {
+	state := int32(0)
+	report := func(end int) bool {
+		for s := state; s >= 0; s = ac.states[s].dict {
+			if p := ac.states[s].out; p >= 0 {
+				if !fn(ac.start(line, end, ac.lengths[p]), end, int(p)) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if !ac.ignoreCase {
+		for i, b := range line {
+			state = ac.step(state, b)
+			if !report(i + 1) {
+				return
+			}
+		}
+		return
+	}
+
+	var folded [utf8.UTFMax]byte
+	for i := 0; i < len(line); {
+		r, size := rune(line[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRune(line[i:])
+		}
+		var chunk []byte
+		if r == utf8.RuneError && size == 1 {
+			chunk = line[i : i+1] // Некорректный UTF-8 сравнивается побайтно
+		} else {
+			chunk = folded[:utf8.EncodeRune(folded[:], foldRune(r))]
+		}
+		for _, b := range chunk {
+			state = ac.step(state, b)
+		}
+		i += size
+		if !report(i) {
+			return
+		}
+	}
+}
+
+// start возвращает начало вхождения шаблона длины length, заканчивающегося в end.
+// Свёртывание регистра сохраняет число рун, но не байт, поэтому с ignoreCase отступаем по рунам.
+func (ac *ahoCorasick) start(line []byte, end, length int) int {
+	if !ac.ignoreCase {
+		return end - length
+	}
+	for ; length > 0; length-- {
+		_, size := utf8.DecodeLastRune(line[:end])
+		end -= size
+	}
+	return end
+}
+
+// fold дописывает к dst байты b со свёрнутым регистром, если он не учитывается.
+func (ac *ahoCorasick) fold(dst, b []byte) []byte {
+	if !ac.ignoreCase {
+		return append(dst, b...)
+	}
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, b[0])
+		} else {
+			dst = utf8.AppendRune(dst, foldRune(r))
+		}
+		b = b[size:]
+	}
+	return dst
+}
+
+// foldRune возвращает представителя класса руны при свёртывании регистра —
+// наименьшую руну орбиты unicode.SimpleFold: 'a', 'A' -> 'A'; 'k', 'K', U+212A -> 'K'.
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		if 'a' <= r && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		return r
+	}
+	least := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < least {
+			least = f
+		}
+	}
+	return least
+}
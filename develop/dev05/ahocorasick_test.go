@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// naiveFold сворачивает регистр строки так же, как автомат, для эталонного поиска.
+func naiveFold(s string) string {
+	return string((&ahoCorasick{ignoreCase: true}).fold(nil, []byte(s)))
+}
+
+// naiveMatches возвращает все вхождения шаблонов в порядке конца вхождения, как scan.
+func naiveMatches(line string, patterns []string, ignoreCase bool) [][3]int {
+	var matches [][3]int
+	for end := 1; end <= len(line); end++ {
+		for start := end - 1; start >= 0; start-- {
+			// Без учёта регистра строка сравнивается по рунам, вхождения не разрезают руну.
+			if ignoreCase && (!utf8.RuneStart(line[start]) || end < len(line) && !utf8.RuneStart(line[end])) {
+				continue
+			}
+			for p, pattern := range patterns {
+				text := line[start:end]
+				if ignoreCase {
+					text, pattern = naiveFold(text), naiveFold(pattern)
+				}
+				if pattern != "" && text == pattern && !hasEarlierDuplicate(patterns, p, ignoreCase) {
+					matches = append(matches, [3]int{start, end, p})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// hasEarlierDuplicate сообщает, что шаблон p повторяет один из предыдущих.
+func hasEarlierDuplicate(patterns []string, p int, ignoreCase bool) bool {
+	for _, other := range patterns[:p] {
+		if other == patterns[p] || ignoreCase && naiveFold(other) == naiveFold(patterns[p]) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortMatches(matches [][3]int) {
+	for i := range matches {
+		for j := i + 1; j < len(matches); j++ {
+			a, b := matches[i], matches[j]
+			if b[1] < a[1] || b[1] == a[1] && (b[0] < a[0] || b[0] == a[0] && b[2] < a[2]) {
+				matches[i], matches[j] = b, a
+			}
+		}
+	}
+}
+
+func TestAhoCorasickScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "A", "B", "ы", "Ы", "k", "K", "\xff"}
+	random := func(n int) string {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			sb.WriteString(alphabet[rng.Intn(len(alphabet))])
+		}
+		return sb.String()
+	}
+
+	for i := 0; i < 500; i++ {
+		patterns := make([]string, 1+rng.Intn(6))
+		for j := range patterns {
+			patterns[j] = random(1 + rng.Intn(3))
+		}
+		line := random(rng.Intn(20))
+		for _, ignoreCase := range []bool{false, true} {
+			var got [][3]int
+			newAhoCorasick(patterns, ignoreCase).scan([]byte(line), func(start, end, p int) bool {
+				got = append(got, [3]int{start, end, p})
+				return true
+			})
+			want := naiveMatches(line, patterns, ignoreCase)
+			sortMatches(got)
+			sortMatches(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("scan(%q, %q, ignoreCase=%v) = %v, want %v", line, patterns, ignoreCase, got, want)
+			}
+		}
+	}
+}
+
+func TestAhoCorasickMatch(t *testing.T) {
+	tests := []struct {
+		patterns   []string
+		ignoreCase bool
+		line       string
+		want       bool
+	}{
+		{[]string{"he", "she", "his", "hers"}, false, "ushers", true},
+		{[]string{"he", "she", "his", "hers"}, false, "HERS", false},
+		{[]string{"he", "she", "his", "hers"}, true, "HERS", true},
+		{[]string{"a.b"}, false, "axb", false}, // Шаблоны — не регулярные выражения
+		{[]string{"a.b"}, false, "xa.by", true},
+		{[]string{"привет"}, true, "ПРИВЕТ, мир", true},
+		{[]string{"ПРИВЕТ"}, true, "привет", true},
+		{[]string{"k"}, true, "\u212a", true}, // Знак кельвина сворачивается в K
+		{[]string{""}, false, "anything", true},
+		{[]string{"x", ""}, false, "", true},
+		{[]string{"abc"}, false, "", false},
+	}
+	for _, tt := range tests {
+		if got := newAhoCorasick(tt.patterns, tt.ignoreCase).Match([]byte(tt.line)); got != tt.want {
+			t.Errorf("Match(%q, %q, ignoreCase=%v) = %v, want %v", tt.patterns, tt.line, tt.ignoreCase, got, tt.want)
+		}
+	}
+}
+
+func TestNewMatcher(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		mo       matchOptions
+		line     string
+		want     bool
+	}{
+		{nil, matchOptions{}, "line", false},
+		{[]string{"fo+", "ba?r"}, matchOptions{}, "xbr", true},
+		{[]string{"fo+", "ba?r"}, matchOptions{}, "f", false},
+		{[]string{"^a", "b$"}, matchOptions{}, "cab", true}, // Якоря действуют внутри своей альтернативы
+		{[]string{"^a", "b$"}, matchOptions{}, "cabc", false},
+		{[]string{"FOO"}, matchOptions{ignoreCase: true}, "foo", true},
+		{[]string{"fo+"}, matchOptions{fixed: true}, "foo", false},
+		{[]string{"fo+"}, matchOptions{fixed: true}, "xfo+", true},
+		{[]string{"foo"}, matchOptions{word: true}, "a foo.", true},
+		{[]string{"foo"}, matchOptions{word: true}, "foobar foo_x", false},
+		{[]string{"foo|foobar"}, matchOptions{word: true}, "foobar", true},
+		{[]string{"слово"}, matchOptions{word: true}, "словом", false}, // Буквы любого алфавита — часть слова
+		{[]string{"слово"}, matchOptions{word: true}, "(слово)", true},
+		{[]string{"foo"}, matchOptions{fixed: true, word: true}, "foobar foo_x", false},
+		{[]string{"foo", "bar"}, matchOptions{fixed: true, word: true}, "foobar bar", true},
+		{[]string{""}, matchOptions{fixed: true, word: true}, "a b", false},
+		{[]string{""}, matchOptions{fixed: true, word: true}, "a  b", true},
+		{[]string{"a|ab"}, matchOptions{line: true}, "ab", true},
+		{[]string{"a|ab"}, matchOptions{line: true}, "abc", false},
+		{[]string{"ab"}, matchOptions{fixed: true, line: true, ignoreCase: true}, "AB", true},
+		{[]string{"ab"}, matchOptions{fixed: true, line: true}, "abab", false},
+	}
+	for _, tt := range tests {
+		m, err := newMatcher(tt.patterns, tt.mo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Match([]byte(tt.line)); got != tt.want {
+			t.Errorf("newMatcher(%q, %+v).Match(%q) = %v, want %v", tt.patterns, tt.mo, tt.line, got, tt.want)
+		}
+	}
+
+	if _, err := newMatcher([]string{"ok", "("}, matchOptions{}); err == nil {
+		t.Error("newMatcher with invalid expression: want error")
+	}
+	if _, err := newMatcher([]string{"("}, matchOptions{word: true}); err == nil {
+		t.Error("newMatcher with invalid expression and -w: want error")
+	}
+}
+
+// TestFindAllSubmatchIndex сравнивает вхождения фиксированных строк с вхождениями
+// тех же строк, экранированных в регулярное выражение.
+func TestFindAllSubmatchIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	alphabet := []string{"a", "b", "ab", " ", "-", "_", "ы"}
+	random := func(n int) string {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			sb.WriteString(alphabet[rng.Intn(len(alphabet))])
+		}
+		return sb.String()
+	}
+
+	for i := 0; i < 1000; i++ {
+		patterns := make([]string, 1+rng.Intn(3))
+		quoted := make([]string, len(patterns))
+		for j := range patterns {
+			patterns[j] = random(1 + rng.Intn(3))
+			quoted[j] = regexp.QuoteMeta(patterns[j])
+		}
+		line := []byte(random(rng.Intn(12)))
+		for _, mo := range []matchOptions{{}, {word: true}, {line: true}} {
+			re, err := newMatcher(quoted, mo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mo.fixed = true
+			fixed, _ := newMatcher(patterns, mo)
+			got, want := fixed.FindAllSubmatchIndex(line, -1), re.FindAllSubmatchIndex(line, -1)
+			if !reflect.DeepEqual(nonEmpty(got), nonEmpty(want)) || fixed.Match(line) != re.Match(line) {
+				t.Fatalf("%q in %q (%+v): fixed = %v, regexp = %v", patterns, line, mo, got, want)
+			}
+		}
+	}
+
+	words, _ := newMatcher([]string{"."}, matchOptions{word: true})
+	if got, want := words.FindAllSubmatchIndex([]byte("  a b"), -1), [][]int{{0, 1}, {2, 3}, {4, 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("-w . in %q = %v, want %v", "  a b", got, want)
+	}
+	if got := words.FindAllSubmatchIndex([]byte("a b c"), 2); len(got) != 2 {
+		t.Errorf("FindAllSubmatchIndex(n=2) = %v, want 2 matches", got)
+	}
+}
+
+// nonEmpty возвращает границы непустых вхождений без групп.
+func nonEmpty(all [][]int) [][]int {
+	var result [][]int
+	for _, loc := range all {
+		if loc[0] != loc[1] {
+			result = append(result, loc[:2])
+		}
+	}
+	return result
+}
+
+func TestPatternsValue(t *testing.T) {
+	var p patternsValue
+	p.Set("a\nb", nil)
+	p.Set("c", nil)
+	if want := (patternsValue{"a", "b", "c"}); !reflect.DeepEqual(p, want) {
+		t.Errorf("patterns = %q, want %q", p, want)
+	}
+}
+
+func TestReadPatterns(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "patterns.txt")
+	if err := os.WriteFile(name, []byte("one\n\nthree"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readPatterns(name)
+	if want := []string{"one", "", "three"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("readPatterns() = %q, %v; want %q", got, err, want)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readPatterns(empty); err != nil || len(got) != 0 {
+		t.Errorf("readPatterns(empty) = %q, %v; want no patterns", got, err)
+	}
+
+	if _, err := readPatterns(filepath.Join(dir, "missing")); err == nil {
+		t.Error("readPatterns(missing): want error")
+	}
+}
+
+// iocs возвращает n индикаторов компрометации вида домен/хеш и строки лога, часть из которых их содержит.
+func iocs(n int) ([]string, [][]byte) {
+	rng := rand.New(rand.NewSource(2))
+	patterns := make([]string, n)
+	for i := range patterns {
+		if i%2 == 0 {
+			patterns[i] = fmt.Sprintf("evil-%d.example.com", rng.Int63())
+		} else {
+			patterns[i] = fmt.Sprintf("%032x", rng.Int63())
+		}
+	}
+	lines := make([][]byte, 1000)
+	for i := range lines {
+		line := fmt.Sprintf("2024-05-01T12:00:%02d host=web%d GET /index.html ref=site-%d.example.org", i%60, i, rng.Int63())
+		if i%100 == 0 {
+			line += " " + patterns[rng.Intn(n)]
+		}
+		lines[i] = []byte(line)
+	}
+	return patterns, lines
+}
+
+func benchmarkMatcher(b *testing.B, fixed, ignoreCase bool, sizes ...int) {
+	for _, n := range sizes {
+		patterns, lines := iocs(n)
+		m, err := newMatcher(patterns, matchOptions{fixed: fixed, ignoreCase: ignoreCase})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if fixed && n <= 100 {
+			// Проверка по регулярному выражению с экранированными шаблонами.
+			quoted := make([]string, len(patterns))
+			for i, p := range patterns {
+				quoted[i] = regexp.QuoteMeta(p)
+			}
+			if re, _ := newMatcher(quoted, matchOptions{ignoreCase: ignoreCase}); !sameMatches(m, re, lines) {
+				b.Fatal("fixed and regexp matchers disagree")
+			}
+		}
+		b.Run(fmt.Sprintf("patterns=%d", n), func(b *testing.B) {
+			b.SetBytes(int64(len(bytes.Join(lines, []byte("\n")))))
+			for i := 0; i < b.N; i++ {
+				for _, line := range lines {
+					m.Match(line)
+				}
+			}
+		})
+	}
+}
+
+func sameMatches(a, b matcher, lines [][]byte) bool {
+	for _, line := range lines {
+		if a.Match(line) != b.Match(line) {
+			return false
+		}
+	}
+	return true
+}
+
+func BenchmarkMatchFixed(b *testing.B) {
+	benchmarkMatcher(b, true, false, 10, 1000, 10000)
+}
+
+func BenchmarkMatchFixedIgnoreCase(b *testing.B) {
+	benchmarkMatcher(b, true, true, 10, 1000, 10000)
+}
+
+// BenchmarkMatchRegexpAlternation — для сравнения: шаблоны одной альтернативой в регулярном выражении.
+// Уже на тысяче шаблонов поиск медленнее автомата на три порядка.
+func BenchmarkMatchRegexpAlternation(b *testing.B) {
+	benchmarkMatcher(b, false, false, 10, 100)
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// groupSeparator разделяет группы строк контекста, которые не идут подряд.
+const groupSeparator = "--"
+
+// Разделители после имени файла и номера строки, как в GNU grep.
+const (
+	selectedMark = ':' // Выбранная строка
+	contextMark  = '-' // Строка контекста
+)
+
+// Цвета --color по умолчанию, как в GNU grep (GREP_COLORS="ms=01;31:fn=35:ln=32:se=36").
+const (
+	colorMatch     = "01;31" // Вхождение
+	colorFilename  = "35"    // Имя файла
+	colorLineNum   = "32"    // Номер строки
+	colorSeparator = "36"    // Разделители ':', '-' и "--"
+)
+
+// colorEnd завершает цветной фрагмент; "\x1b[K" очищает остаток строки терминала цветом фона.
+const colorEnd = "\x1b[m\x1b[K"
+
+// colorStart начинает цветной фрагмент с атрибутами SGR sgr.
+func colorStart(sgr string) string {
+	return "\x1b[" + sgr + "m\x1b[K"
+}
+
+// listMode — вывод имён файлов вместо строк.
+type listMode int
+
+const (
+	listNone        listMode = iota
+	listMatching             // Файлы с выбранными строками (-l)
+	listNonMatching          // Файлы без выбранных строк (-L)
+)
+
+// options — параметры поиска.
+type options struct {
+	after    int      // Строк контекста после совпадения (-A)
+	before   int      // Строк контекста до совпадения (-B)
+	count    bool     // Выводить только количество выбранных строк (-c)
+	list     listMode // Выводить только имя файла (-l, -L); важнее count
+	invert   bool     // Выбирать несовпадающие строки (-v)
+	number   bool     // Печатать номера строк (-n)
+	context  bool     // Контекст задан явно, пусть и нулевой: группы разделяются строкой "--"
+	maxCount int      // Остановиться после стольких выбранных строк (-m); 0 — без ограничения
+	maxLine  int      // Максимальная длина строки в байтах; 0 означает maxLineSize
+
+	onlyMatching bool // Выводить только вхождения, каждое с новой строки (-o)
+	json         bool // Выводить вхождения объектами JSON, по одному в строке (--json)
+	color        bool // Выделять вхождения, имена файлов и номера строк цветом (--color)
+
+	filename     string // Имя входа для -l, -L, --json и префикса строк
+	withFilename bool   // Печатать имя файла перед каждой строкой вывода
+}
+
+// summary сообщает, что вместо строк выводится итог по входу: количество или имя файла.
+func (o options) summary() bool {
+	return o.count || o.list != listNone
+}
+
+// separated сообщает, разделяются ли группы строк строкой "--".
+func (o options) separated() bool {
+	return !o.summary() && !o.json && (o.context || o.before > 0 || o.after > 0)
+}
+
+// separatorLine возвращает строку-разделитель групп с переводом строки.
+func (o options) separatorLine() string {
+	if o.color {
+		return colorStart(colorSeparator) + groupSeparator + colorEnd + "\n"
+	}
+	return groupSeparator + "\n"
+}
+
+// grep читает r построчно и выводит в w выбранные строки с контекстом.
+// В памяти держатся только текущая строка и до opts.before строк контекста,
+// поэтому размер входа не ограничен. Вывод сбрасывается всякий раз, когда прочитанные
+// данные закончились и следующая строка ещё не поступила, так что grep можно
+// использовать на бесконечном потоке (tail -f | grep).
+// Возвращает число выбранных строк.
+func grep(r io.Reader, w io.Writer, match matcher, opts options) (int, error) {
+	if opts.maxLine <= 0 {
+		opts.maxLine = maxLineSize
+	}
+	if opts.list != listNone {
+		opts.maxCount = 1 // Для списка файлов достаточно первой выбранной строки
+	}
+	if opts.summary() || opts.json {
+		opts.before, opts.after = 0, 0 // Итог и JSON не содержат строк контекста
+	}
+
+	bw := bufio.NewWriter(w)
+	p := &printer{w: bw, match: match, opts: opts, json: json.NewEncoder(bw)}
+	p.json.SetEscapeHTML(false)
+	n, err := p.run(newLineReader(r, opts.maxLine))
+	if flushErr := p.w.Flush(); err == nil {
+		err = flushErr
+	}
+	return n, err
+}
+
+// printer выводит строки, разделяя группы строк, которые не идут подряд.
+type printer struct {
+	w      *bufio.Writer
+	match  matcher
+	opts   options
+	json   *json.Encoder
+	last   int   // Номер последней выведенной строки; 0 — строк ещё не было
+	offset int64 // Смещение текущей строки от начала входа в байтах
+}
+
+// run выбирает строки из lr и выводит их с контекстом. После opts.maxCount выбранных
+// строк выводится контекст после последней из них, и чтение прекращается.
+func (p *printer) run(lr *lineReader) (int, error) {
+	opts := p.opts
+	before := newRing(opts.before)
+	limited := func(selected int) bool {
+		return opts.maxCount > 0 && selected >= opts.maxCount
+	}
+
+	selected, afterLeft := 0, 0
+	for num := 1; !limited(selected) || afterLeft > 0; num++ {
+		line, err := lr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return selected, fmt.Errorf("read line %d: %w", num, err)
+		}
+
+		switch {
+		case !limited(selected) && p.match.Match(line) != opts.invert:
+			selected++
+			if opts.summary() {
+				break
+			}
+			err = before.drain(func(back int, line []byte) error {
+				return p.print(num-back, line, contextMark)
+			})
+			if err == nil {
+				err = p.print(num, line, selectedMark)
+			}
+			afterLeft = opts.after
+		case afterLeft > 0:
+			err = p.print(num, line, contextMark)
+			afterLeft--
+		default:
+			before.push(line)
+		}
+		if err != nil {
+			return selected, err
+		}
+		p.offset += int64(len(line)) + 1
+
+		if !lr.buffered() {
+			if err := p.w.Flush(); err != nil {
+				return selected, err
+			}
+		}
+	}
+
+	switch {
+	case opts.list == listMatching && selected > 0, opts.list == listNonMatching && selected == 0:
+		p.paint(colorFilename, opts.filename)
+		return selected, p.w.WriteByte('\n')
+	case opts.list == listNone && opts.count:
+		if opts.withFilename {
+			p.paint(colorFilename, opts.filename)
+			p.paint(colorSeparator, string(selectedMark))
+		}
+		_, err := fmt.Fprintln(p.w, selected)
+		return selected, err
+	}
+	return selected, nil
+}
+
+// print выводит строку num; mark отличает выбранные строки от строк контекста.
+// Перед строкой, которая не продолжает предыдущую группу, выводится разделитель групп.
+func (p *printer) print(num int, line []byte, mark byte) error {
+	if p.opts.separated() && p.last > 0 && num != p.last+1 {
+		if _, err := p.w.WriteString(p.opts.separatorLine()); err != nil {
+			return err
+		}
+	}
+	p.last = num
+
+	// Вхождения есть в строках, совпавших с шаблонами: в выбранных, а с -v — в строках контекста.
+	matching := (mark == selectedMark) != p.opts.invert
+	switch {
+	case p.opts.json:
+		if matching {
+			return p.printJSON(num, line)
+		}
+		return nil
+	case p.opts.onlyMatching:
+		if matching {
+			return p.printMatches(num, line, mark)
+		}
+		return nil
+	}
+
+	p.prefix(num, mark)
+	if p.opts.color && matching {
+		p.highlight(line)
+	} else {
+		p.w.Write(line)
+	}
+	return p.w.WriteByte('\n')
+}
+
+// prefix выводит имя файла и номер строки перед строкой вывода.
+func (p *printer) prefix(num int, mark byte) {
+	if p.opts.withFilename {
+		p.paint(colorFilename, p.opts.filename)
+		p.paint(colorSeparator, string(mark))
+	}
+	if p.opts.number {
+		p.paint(colorLineNum, strconv.Itoa(num))
+		p.paint(colorSeparator, string(mark))
+	}
+}
+
+// paint выводит text цветом sgr, если задан --color.
+func (p *printer) paint(sgr, text string) {
+	if !p.opts.color {
+		p.w.WriteString(text)
+		return
+	}
+	p.w.WriteString(colorStart(sgr))
+	p.w.WriteString(text)
+	p.w.WriteString(colorEnd)
+}
+
+// highlight выводит строку, выделяя вхождения цветом. Пустые вхождения не выделяются.
+func (p *printer) highlight(line []byte) {
+	pos := 0
+	for _, loc := range p.match.FindAllSubmatchIndex(line, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		p.w.Write(line[pos:loc[0]])
+		p.w.WriteString(colorStart(colorMatch))
+		p.w.Write(line[loc[0]:loc[1]])
+		p.w.WriteString(colorEnd)
+		pos = loc[1]
+	}
+	p.w.Write(line[pos:])
+}
+
+// printMatches выводит каждое непустое вхождение отдельной строкой (-o).
+func (p *printer) printMatches(num int, line []byte, mark byte) error {
+	for _, loc := range p.match.FindAllSubmatchIndex(line, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		p.prefix(num, mark)
+		if p.opts.color {
+			p.w.WriteString(colorStart(colorMatch))
+		}
+		p.w.Write(line[loc[0]:loc[1]])
+		if p.opts.color {
+			p.w.WriteString(colorEnd)
+		}
+		if err := p.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonMatch — вхождение в выводе --json. Границы — смещения в байтах от начала строки.
+type jsonMatch struct {
+	File   string       `json:"file,omitempty"`
+	Line   int          `json:"line"`
+	Offset int64        `json:"offset"` // Смещение строки от начала входа в байтах
+	Start  int          `json:"start"`
+	End    int          `json:"end"`
+	Text   string       `json:"text"`
+	Groups []*jsonGroup `json:"groups"` // null на месте группы, не участвовавшей в совпадении
+}
+
+// jsonGroup — группа регулярного выражения во вхождении.
+type jsonGroup struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// printJSON выводит каждое непустое вхождение объектом JSON.
+func (p *printer) printJSON(num int, line []byte) error {
+	for _, loc := range p.match.FindAllSubmatchIndex(line, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		m := jsonMatch{
+			File:   p.opts.filename,
+			Line:   num,
+			Offset: p.offset,
+			Start:  loc[0],
+			End:    loc[1],
+			Text:   string(line[loc[0]:loc[1]]),
+			Groups: []*jsonGroup{},
+		}
+		for i := 2; i+1 < len(loc); i += 2 {
+			var g *jsonGroup
+			if loc[i] >= 0 {
+				g = &jsonGroup{Start: loc[i], End: loc[i+1], Text: string(line[loc[i]:loc[i+1]])}
+			}
+			m.Groups = append(m.Groups, g)
+		}
+		if err := p.json.Encode(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
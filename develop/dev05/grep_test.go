@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrep(t *testing.T) {
+	input := "a\nb\nmatch\nc\nd\ne\nmatch2\nf\ng\nh\nmatch3"
+	tests := []struct {
+		name string
+		opts options
+		want string
+		n    int
+	}{
+		{
+			name: "без контекста группы не разделяются",
+			opts: options{number: true},
+			want: "3:match\n7:match2\n11:match3\n",
+			n:    3,
+		},
+		{
+			name: "контекст до и после",
+			opts: options{before: 1, after: 1, number: true},
+			want: "2-b\n3:match\n4-c\n--\n6-e\n7:match2\n8-f\n--\n10-h\n11:match3\n",
+			n:    3,
+		},
+		{
+			name: "только после",
+			opts: options{after: 1},
+			want: "match\nc\n--\nmatch2\nf\n--\nmatch3\n",
+			n:    3,
+		},
+		{
+			name: "только до",
+			opts: options{before: 2, number: true},
+			want: "1-a\n2-b\n3:match\n--\n5-d\n6-e\n7:match2\n--\n9-g\n10-h\n11:match3\n",
+			n:    3,
+		},
+		{
+			name: "пересекающиеся окна объединяются",
+			opts: options{before: 3, after: 2, number: true},
+			want: "1-a\n2-b\n3:match\n4-c\n5-d\n6-e\n7:match2\n8-f\n9-g\n10-h\n11:match3\n",
+			n:    3,
+		},
+		{
+			name: "соприкасающиеся окна без разделителя",
+			opts: options{after: 1, before: 2},
+			want: "a\nb\nmatch\nc\nd\ne\nmatch2\nf\ng\nh\nmatch3\n",
+			n:    3,
+		},
+		{
+			name: "инверсия с контекстом",
+			opts: options{invert: true, after: 1, number: true},
+			want: "1:a\n2:b\n3-match\n4:c\n5:d\n6:e\n7-match2\n8:f\n9:g\n10:h\n11-match3\n",
+			n:    8,
+		},
+		{
+			name: "нулевой контекст разделяет группы",
+			opts: options{context: true},
+			want: "match\n--\nmatch2\n--\nmatch3\n",
+			n:    3,
+		},
+		{
+			name: "подсчёт",
+			opts: options{count: true, before: 5},
+			want: "3\n",
+			n:    3,
+		},
+		{
+			name: "инверсия с подсчётом",
+			opts: options{count: true, invert: true},
+			want: "8\n",
+			n:    8,
+		},
+	}
+	expression := regexp.MustCompile("match")
+	for _, tt := range tests {
+		var out bytes.Buffer
+		n, err := grep(strings.NewReader(input), &out, expression, tt.opts)
+		if err != nil || n != tt.n || out.String() != tt.want {
+			t.Errorf("%s: grep() = %d, %v, %q; want %d, %q", tt.name, n, err, out.String(), tt.n, tt.want)
+		}
+	}
+}
+
+func TestGrepOutputModes(t *testing.T) {
+	input := "foo bar foo\nbaz\nfoo\nqux\nbar\n"
+	const (
+		red   = "\x1b[01;31m\x1b[K"
+		green = "\x1b[32m\x1b[K"
+		cyan  = "\x1b[36m\x1b[K"
+		end   = "\x1b[m\x1b[K"
+	)
+	tests := []struct {
+		name string
+		opts options
+		want string
+		n    int
+	}{
+		{
+			name: "только вхождения",
+			opts: options{onlyMatching: true, number: true},
+			want: "1:foo\n1:foo\n3:foo\n",
+			n:    2,
+		},
+		{
+			name: "только вхождения: строки контекста не выводятся",
+			opts: options{onlyMatching: true, after: 1, maxCount: 1, context: true},
+			want: "foo\nfoo\n",
+			n:    1,
+		},
+		{
+			name: "остановка после -m с контекстом после последней строки",
+			opts: options{maxCount: 1, after: 2, number: true},
+			want: "1:foo bar foo\n2-baz\n3-foo\n",
+			n:    1,
+		},
+		{
+			name: "подсчёт с -m",
+			opts: options{maxCount: 1, count: true},
+			want: "1\n",
+			n:    1,
+		},
+		{
+			name: "файл с совпадениями",
+			opts: options{list: listMatching, count: true, filename: "in.txt"},
+			want: "in.txt\n",
+			n:    1,
+		},
+		{
+			name: "файл без совпадений не выводится с -L",
+			opts: options{list: listNonMatching, filename: "in.txt"},
+			want: "",
+			n:    1,
+		},
+		{
+			name: "цвет",
+			opts: options{color: true, number: true},
+			want: green + "1" + end + cyan + ":" + end + red + "foo" + end + " bar " + red + "foo" + end + "\n" +
+				green + "3" + end + cyan + ":" + end + red + "foo" + end + "\n",
+			n: 2,
+		},
+		{
+			name: "цвет с -v: выделяются вхождения в строках контекста",
+			opts: options{color: true, invert: true, before: 1, maxCount: 1},
+			want: red + "foo" + end + " bar " + red + "foo" + end + "\nbaz\n",
+			n:    1,
+		},
+		{
+			name: "JSON",
+			opts: options{json: true, before: 1, filename: "in.txt"},
+			want: `{"file":"in.txt","line":1,"offset":0,"start":0,"end":3,"text":"foo","groups":[{"start":1,"end":3,"text":"oo"}]}` + "\n" +
+				`{"file":"in.txt","line":1,"offset":0,"start":8,"end":11,"text":"foo","groups":[{"start":9,"end":11,"text":"oo"}]}` + "\n" +
+				`{"file":"in.txt","line":3,"offset":16,"start":0,"end":3,"text":"foo","groups":[{"start":1,"end":3,"text":"oo"}]}` + "\n",
+			n: 2,
+		},
+	}
+	expression := regexp.MustCompile("f(o+)")
+	for _, tt := range tests {
+		var out bytes.Buffer
+		n, err := grep(strings.NewReader(input), &out, expression, tt.opts)
+		if err != nil || n != tt.n || out.String() != tt.want {
+			t.Errorf("%s: grep() = %d, %v, %q; want %d, %q", tt.name, n, err, out.String(), tt.n, tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	if n, _ := grep(strings.NewReader(input), &out, regexp.MustCompile("none"), options{list: listNonMatching, filename: "in.txt"}); n != 0 || out.String() != "in.txt\n" {
+		t.Errorf("grep(-L, no matches) = %d, %q; want 0, %q", n, out.String(), "in.txt\n")
+	}
+}
+
+func TestGrepLongLines(t *testing.T) {
+	long := strings.Repeat("x", 200<<10) + "match" // Длиннее буфера bufio.Scanner по умолчанию
+	input := "a\n" + long + "\nb\n"
+
+	var out bytes.Buffer
+	n, err := grep(strings.NewReader(input), &out, regexp.MustCompile("match$"), options{before: 1})
+	if want := "a\n" + long + "\n"; err != nil || n != 1 || out.String() != want {
+		t.Errorf("grep(long line) = %d, %v, %d bytes; want 1 match, %d bytes", n, err, out.Len(), len(want))
+	}
+
+	_, err = grep(strings.NewReader(input), io.Discard, regexp.MustCompile("match"), options{maxLine: 100 << 10})
+	if !errors.Is(err, errLineTooLong) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("grep(line over limit) = %v, want errLineTooLong at line 2", err)
+	}
+}
+
+func TestLineReaderLimit(t *testing.T) {
+	// Ровно max байт проходят с переводом строки и без него, max+1 — нет.
+	for _, input := range []string{"abcd\n", "abcd"} {
+		lr := newLineReader(strings.NewReader(input), 4)
+		if line, err := lr.next(); err != nil || string(line) != "abcd" {
+			t.Errorf("next(%q) = %q, %v", input, line, err)
+		}
+		if _, err := lr.next(); err != io.EOF {
+			t.Errorf("next(%q) at end = %v, want io.EOF", input, err)
+		}
+	}
+	lr := newLineReader(strings.NewReader("abcde"), 4)
+	if _, err := lr.next(); !errors.Is(err, errLineTooLong) {
+		t.Errorf("next(abcde) = %v, want errLineTooLong", err)
+	}
+}
+
+// failingReader возвращает данные, а затем ошибку чтения.
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestGrepReadError(t *testing.T) {
+	readErr := errors.New("device error")
+	var out bytes.Buffer
+	n, err := grep(&failingReader{data: "match\nmatch\n", err: readErr}, &out, regexp.MustCompile("match"), options{})
+	if !errors.Is(err, readErr) || n != 2 {
+		t.Errorf("grep() = %d, %v; want 2, %v", n, err, readErr)
+	}
+	if !strings.Contains(out.String(), "match") {
+		t.Errorf("lines read before the error were not written: %q", out.String())
+	}
+}
+
+// TestGrepStreaming проверяет, что совпадение выводится сразу, не дожидаясь конца ввода.
+func TestGrepStreaming(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := grep(inR, outW, regexp.MustCompile("error"), options{before: 1})
+		outW.Close()
+		done <- err
+	}()
+
+	lines := bufio.NewReader(outR)
+	readLine := func() string {
+		result := make(chan string, 1)
+		go func() {
+			line, _ := lines.ReadString('\n')
+			result <- line
+		}()
+		select {
+		case line := <-result:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("grep did not flush output while waiting for input")
+			return ""
+		}
+	}
+
+	io.WriteString(inW, "info\nerror 1\n")
+	for _, want := range []string{"info\n", "error 1\n"} {
+		if got := readLine(); got != want {
+			t.Errorf("output line = %q, want %q", got, want)
+		}
+	}
+
+	io.WriteString(inW, "trace\ndebug\nerror 2\n")
+	for _, want := range []string{"--\n", "debug\n", "error 2\n"} {
+		if got := readLine(); got != want {
+			t.Errorf("output line = %q, want %q", got, want)
+		}
+	}
+
+	inW.Close()
+	go io.Copy(io.Discard, outR)
+	if err := <-done; err != nil {
+		t.Errorf("grep() = %v", err)
+	}
+}
+
+func TestRing(t *testing.T) {
+	r := newRing(2)
+	for _, line := range []string{"a", "b", "c"} {
+		r.push([]byte(line))
+	}
+	var got []string
+	r.drain(func(back int, line []byte) error {
+		got = append(got, string(line)+":"+string(rune('0'+back)))
+		return nil
+	})
+	if want := []string{"b:2", "c:1"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("drain() = %q, want %q", got, want)
+	}
+	if r.size != 0 {
+		t.Errorf("size after drain = %d, want 0", r.size)
+	}
+
+	// После очистки слоты переиспользуются, порядок строк сохраняется.
+	for _, line := range []string{"d", "e", "f", "g"} {
+		r.push([]byte(line))
+	}
+	got = got[:0]
+	r.drain(func(back int, line []byte) error {
+		got = append(got, string(line))
+		return nil
+	})
+	if want := []string{"f", "g"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("drain() after reuse = %q, want %q", got, want)
+	}
+}
+
+// TestGrepHugeBefore проверяет, что огромный -B не выделяет память под всё окно заранее.
+func TestGrepHugeBefore(t *testing.T) {
+	var out bytes.Buffer
+	n, err := grep(strings.NewReader("a\nb\n"), &out, regexp.MustCompile("b"), options{before: math.MaxInt})
+	if err != nil || n != 1 || out.String() != "a\nb\n" {
+		t.Errorf("grep(-B MaxInt) = %d, %v, %q; want 1, %q", n, err, out.String(), "a\nb\n")
+	}
+	if r := newRing(math.MaxInt); cap(r.lines) != 0 {
+		t.Errorf("newRing(MaxInt) preallocated %d slots", cap(r.lines))
+	}
+}
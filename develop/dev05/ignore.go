@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ignoreFileName — файл с правилами игнорирования в каталоге.
+const ignoreFileName = ".gitignore"
+
+// ignoreRule — правило файла .gitignore.
+type ignoreRule struct {
+	re      *regexp.Regexp // Путь относительно каталога файла правил
+	negate  bool           // Правило "!pattern" возвращает путь в поиск
+	dirOnly bool           // Правило "pattern/" действует только на каталоги
+}
+
+// ignoreRules — правила одного файла .gitignore и правила родительских каталогов.
+type ignoreRules struct {
+	parent *ignoreRules
+	base   string // Каталог файла правил в том виде, в каком он строится обходом
+	rules  []ignoreRule
+}
+
+// load читает файл правил каталога dir и возвращает правила с учётом родительских.
+// Если файла нет, возвращаются правила родителя.
+func (r *ignoreRules) load(dir string) (*ignoreRules, error) {
+	data, err := os.ReadFile(joinPath(dir, ignoreFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, err
+	}
+
+	child := &ignoreRules{parent: r, base: dir}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rule, ok := parseIgnoreRule(line); ok {
+			child.rules = append(child.rules, rule)
+		}
+	}
+	if len(child.rules) == 0 {
+		return r, nil
+	}
+	return child, nil
+}
+
+// ignored сообщает, исключён ли путь. Правила проверяются от корня к вложенным каталогам
+// и по порядку в файле; решает последнее совпавшее правило, как в git.
+func (r *ignoreRules) ignored(path string, dir bool) bool {
+	if r == nil {
+		return false
+	}
+	ignored := r.parent.ignored(path, dir)
+
+	rel := path
+	if r.base != "" {
+		rel = strings.TrimPrefix(strings.TrimPrefix(path, r.base), "/")
+	}
+	for _, rule := range r.rules {
+		if rule.dirOnly && !dir {
+			continue
+		}
+		if rule.re.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// parseIgnoreRule разбирает строку .gitignore. Пустые строки, комментарии
+// и некорректные шаблоны пропускаются.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	switch {
+	case line[0] == '!':
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, "\\!"), strings.HasPrefix(line, "\\#"):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// Шаблон с "/" в начале или середине привязан к каталогу файла правил,
+	// без "/" — совпадает с именем на любой глубине.
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !strings.Contains(line, "/") {
+		sb.WriteString("(?:.*/)?")
+	}
+	writeGlobRegexp(&sb, strings.TrimPrefix(line, "/"))
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// writeGlobRegexp переводит шаблон .gitignore в регулярное выражение:
+// "*" и "?" не пересекают "/", "**" совпадает с любым числом каталогов.
+func writeGlobRegexp(sb *strings.Builder, glob string) {
+	for i := 0; i < len(glob); i++ {
+		segmentStart := i == 0 || glob[i-1] == '/'
+		switch c := glob[i]; {
+		case segmentStart && strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case segmentStart && glob[i:] == "**":
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+}
+
+// joinPath соединяет каталог в том виде, в каком его задал пользователь, и имя.
+// В отличие от filepath.Join путь не нормализуется, чтобы имена в выводе
+// начинались так же, как аргумент командной строки; пустой каталог — текущий.
+func joinPath(dir, name string) string {
+	switch {
+	case dir == "":
+		return name
+	case strings.HasSuffix(dir, "/"):
+		return dir + name
+	}
+	return dir + "/" + name
+}
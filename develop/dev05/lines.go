@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// maxLineSize — ограничение длины строки по умолчанию. Строка целиком держится в памяти,
+// поэтому без ограничения поток без переводов строки занял бы всю память.
+const maxLineSize = 64 << 20
+
+// errLineTooLong — строка длиннее допустимого размера.
+var errLineTooLong = errors.New("line too long")
+
+// lineReader читает поток построчно без ограничения bufio.Scanner в 64 КБ:
+// строки длиннее внутреннего буфера собираются из частей, пока не превысят max байт.
+type lineReader struct {
+	r   *bufio.Reader
+	buf []byte // Собранная из частей длинная строка
+	max int
+}
+
+// newLineReader создаёт lineReader со строками не длиннее max байт.
+func newLineReader(r io.Reader, max int) *lineReader {
+	return &lineReader{r: bufio.NewReader(r), max: max}
+}
+
+// next возвращает следующую строку без завершающего '\n' или io.EOF в конце потока.
+// Последняя строка может не заканчиваться переводом строки.
+// Возвращаемый срез действителен до следующего вызова next.
+func (lr *lineReader) next() ([]byte, error) {
+	lr.buf = lr.buf[:0]
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		size := len(lr.buf) + len(chunk)
+		if err == nil {
+			size-- // Завершающий '\n' не входит в длину строки
+		}
+		if size > lr.max {
+			return nil, errLineTooLong
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			lr.buf = append(lr.buf, chunk...)
+			continue
+		case err == io.EOF:
+			if len(lr.buf)+len(chunk) == 0 {
+				return nil, io.EOF
+			}
+		case err != nil:
+			return nil, err
+		}
+
+		line := chunk
+		if len(lr.buf) > 0 {
+			lr.buf = append(lr.buf, chunk...)
+			line = lr.buf
+		}
+		if n := len(line); n > 0 && line[n-1] == '\n' {
+			line = line[:n-1]
+		}
+		return line, nil
+	}
+}
+
+// buffered сообщает, что уже прочитанные данные ещё не разобраны на строки,
+// и следующий вызов next не будет ждать ввода.
+func (lr *lineReader) buffered() bool {
+	return lr.r.Buffered() > 0
+}
+
+// ring хранит копии последних строк для контекста перед совпадением (-B).
+// Слоты выделяются по мере поступления строк, поэтому большой -B на коротком входе
+// не занимает память заранее.
+type ring struct {
+	lines [][]byte
+	limit int // Наибольшее число строк
+	start int // Индекс самой старой строки
+	size  int
+}
+
+// newRing создаёт кольцевой буфер на n строк.
+func newRing(n int) *ring {
+	return &ring{limit: n}
+}
+
+// push добавляет копию строки, вытесняя самую старую при переполнении.
+func (r *ring) push(line []byte) {
+	switch {
+	case r.limit <= 0:
+		return
+	case r.size == len(r.lines) && len(r.lines) < r.limit:
+		// Буфер ещё растёт: старые строки вытесняются только после заполнения,
+		// так что здесь start == 0, и новый слот — последний.
+		r.lines = append(r.lines, nil)
+	}
+	slot := (r.start + r.size) % len(r.lines)
+	if r.size == len(r.lines) {
+		slot = r.start
+		r.start = (r.start + 1) % len(r.lines)
+	} else {
+		r.size++
+	}
+	r.lines[slot] = append(r.lines[slot][:0], line...) // Память слотов переиспользуется
+}
+
+// drain вызывает fn для строк буфера от старых к новым и очищает буфер.
+// Аргумент back — на сколько строк строка предшествует следующей за буфером.
+func (r *ring) drain(fn func(back int, line []byte) error) error {
+	for i := 0; i < r.size; i++ {
+		if err := fn(r.size-i, r.lines[(r.start+i)%len(r.lines)]); err != nil {
+			return err
+		}
+	}
+	r.start, r.size = 0, 0
+	return nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pborman/getopt"
+)
+
+// matcher проверяет, подходит ли строка под шаблоны поиска, и находит вхождения для -o,
+// --color и --json. FindAllSubmatchIndex ведёт себя как одноимённый метод *regexp.Regexp:
+// возвращает не больше n (все при n < 0) непересекающихся вхождений слева направо,
+// для каждого — границы вхождения, а затем групп.
+type matcher interface {
+	Match(line []byte) bool
+	FindAllSubmatchIndex(line []byte, n int) [][]int
+}
+
+// matchNone не совпадает ни с одной строкой: так ведёт себя grep с пустым списком шаблонов.
+type matchNone struct{}
+
+func (matchNone) Match([]byte) bool                        { return false }
+func (matchNone) FindAllSubmatchIndex([]byte, int) [][]int { return nil }
+
+// matchOptions — как шаблоны сравниваются со строкой.
+type matchOptions struct {
+	fixed      bool // Фиксированные строки, а не регулярные выражения (-F)
+	ignoreCase bool // Без учёта регистра (-i)
+	word       bool // Вхождение — отдельное слово (-w)
+	line       bool // Вхождение — вся строка (-x); важнее word
+}
+
+// newMatcher создаёт matcher, совпадающий со строкой, если с ней совпадает хотя бы один шаблон.
+// Фиксированные строки ищутся автоматом Ахо — Корасик, регулярные выражения объединяются
+// в одно выражение-альтернативу.
+func newMatcher(patterns []string, mo matchOptions) (matcher, error) {
+	switch {
+	case len(patterns) == 0:
+		return matchNone{}, nil
+	case mo.fixed && (mo.word || mo.line):
+		return &boundedFixed{ac: newAhoCorasick(patterns, mo.ignoreCase), line: mo.line}, nil
+	case mo.fixed:
+		return newAhoCorasick(patterns, mo.ignoreCase), nil
+	}
+
+	expr := patterns[0]
+	if len(patterns) > 1 {
+		alternatives := make([]string, len(patterns))
+		for i, pattern := range patterns {
+			alternatives[i] = "(?:" + pattern + ")"
+		}
+		expr = strings.Join(alternatives, "|")
+	}
+	switch {
+	case mo.word && !mo.line:
+		return newWordRegexp(expr, mo.ignoreCase)
+	case mo.line:
+		expr = "^(?:" + expr + ")$"
+	}
+	re, err := getExpression(expr, mo.ignoreCase)
+	if err != nil {
+		return nil, err
+	}
+	re.Longest() // Как и в grep, для -o и --color из вхождений с одним началом выбирается самое длинное
+	return re, nil
+}
+
+// nonWordClass — символ, который не может быть частью слова. Как и в grep, слово
+// состоит из букв, цифр и знака подчёркивания.
+const nonWordClass = `[^\pL\pN_]`
+
+// isWordRune сообщает, что руна может быть частью слова.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
+}
+
+// wordRegexp ищет регулярное выражение только целыми словами (-w). В RE2 нет проверок
+// на соседние символы без их захвата, поэтому выражение обрамляется символами-границами,
+// а само слово — группа 1.
+type wordRegexp struct {
+	re            *regexp.Regexp // Слово в начале строки или после границы
+	atStart       *regexp.Regexp // Слово с начала фрагмента, перед которым граница слова
+	afterBoundary *regexp.Regexp // Слово после символа-границы
+}
+
+func newWordRegexp(expr string, ignoreCase bool) (*wordRegexp, error) {
+	compile := func(prefix string) (*regexp.Regexp, error) {
+		re, err := getExpression(prefix+"("+expr+")(?:"+nonWordClass+"|$)", ignoreCase)
+		if err != nil {
+			return nil, err
+		}
+		re.Longest()
+		return re, nil
+	}
+	re, err := compile("(?:^|" + nonWordClass + ")")
+	if err != nil {
+		return nil, err
+	}
+	atStart, err := compile("^")
+	if err != nil {
+		return nil, err
+	}
+	afterBoundary, err := compile(nonWordClass)
+	if err != nil {
+		return nil, err
+	}
+	return &wordRegexp{re: re, atStart: atStart, afterBoundary: afterBoundary}, nil
+}
+
+func (w *wordRegexp) Match(line []byte) bool {
+	return w.re.Match(line)
+}
+
+// FindAllSubmatchIndex ищет слова по очереди с конца предыдущего слова: граница после слова
+// захватывается выражением, поэтому поиск начинается сразу за словом, а не за границей.
+// Слово, которое начинается прямо там, проверяется отдельно: с самым длинным вхождением
+// выражение с "^|" в начале могло бы захватить первый символ слова как границу.
+func (w *wordRegexp) FindAllSubmatchIndex(line []byte, n int) [][]int {
+	var all [][]int
+	for from := 0; from <= len(line) && (n < 0 || len(all) < n); {
+		var loc []int
+		if r, _ := utf8.DecodeLastRune(line[:from]); from == 0 || !isWordRune(r) {
+			loc = w.atStart.FindSubmatchIndex(line[from:])
+		}
+		if loc == nil {
+			loc = w.afterBoundary.FindSubmatchIndex(line[from:])
+		}
+		if loc == nil {
+			break
+		}
+		loc = loc[2:] // Без границ слова
+		for i := range loc {
+			if loc[i] >= 0 {
+				loc[i] += from
+			}
+		}
+		all = append(all, loc)
+
+		if loc[1] > from {
+			from = loc[1]
+		} else {
+			_, size := utf8.DecodeRune(line[from:])
+			from += max(size, 1)
+		}
+	}
+	return all
+}
+
+// boundedFixed ищет фиксированные строки только целыми словами (-w) или всей строкой (-x).
+type boundedFixed struct {
+	ac   *ahoCorasick
+	line bool
+}
+
+// bounded сообщает, что вхождение [start, end) — вся строка или отдельное слово.
+func (b *boundedFixed) bounded(line []byte, start, end int) bool {
+	if b.line {
+		return start == 0 && end == len(line)
+	}
+	before, _ := utf8.DecodeLastRune(line[:start])
+	after, _ := utf8.DecodeRune(line[end:])
+	return (start == 0 || !isWordRune(before)) && (end == len(line) || !isWordRune(after))
+}
+
+// matchesEmpty сообщает, что пустой шаблон совпадает со строкой: пустое вхождение
+// должно стоять между двумя границами слова или занимать всю (пустую) строку.
+func (b *boundedFixed) matchesEmpty(line []byte) bool {
+	if b.line || len(line) == 0 {
+		return len(line) == 0
+	}
+	for i := 0; i <= len(line); {
+		if b.bounded(line, i, i) {
+			return true
+		}
+		if i == len(line) {
+			break
+		}
+		_, size := utf8.DecodeRune(line[i:])
+		i += size
+	}
+	return false
+}
+
+func (b *boundedFixed) Match(line []byte) bool {
+	if b.ac.empty && b.matchesEmpty(line) {
+		return true
+	}
+	found := false
+	b.ac.scan(line, func(start, end, _ int) bool {
+		found = b.bounded(line, start, end)
+		return !found
+	})
+	return found
+}
+
+func (b *boundedFixed) FindAllSubmatchIndex(line []byte, n int) [][]int {
+	return b.ac.findAll(line, n, func(start, end int) bool {
+		return b.bounded(line, start, end)
+	})
+}
+
+// patternsValue накапливает шаблоны, заданные повторяющимся флагом -e.
+// Как и в grep, значение с переводами строк задаёт несколько шаблонов.
+type patternsValue []string
+
+func (p *patternsValue) Set(value string, _ getopt.Option) error {
+	*p = append(*p, strings.Split(value, "\n")...)
+	return nil
+}
+
+func (p *patternsValue) String() string {
+	return ""
+}
+
+// filesValue накапливает имена файлов, заданные повторяющимся флагом.
+type filesValue []string
+
+func (f *filesValue) Set(value string, _ getopt.Option) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func (f *filesValue) String() string {
+	return ""
+}
+
+// readPatterns читает шаблоны из файла, по одному в строке; "-" означает stdin.
+// Пустой файл не содержит шаблонов, а пустая строка — пустой шаблон, совпадающий с любой строкой.
+func readPatterns(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var patterns []string
+	lr := newLineReader(r, maxLineSize)
+	for {
+		line, err := lr.next()
+		if err == io.EOF {
+			return patterns, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		patterns = append(patterns, string(line))
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// maxDefaultParallel ограничивает число потоков поиска по умолчанию.
+const maxDefaultParallel = 8
+
+// binaryPeekSize — сколько байт в начале файла проверяется на признаки двоичного файла.
+const binaryPeekSize = 8 << 10
+
+// errBinaryMatches сообщает, что в двоичном файле есть выбранные строки. Это не ошибка:
+// сообщение выводится в stderr, как в grep, но код возврата от него не меняется.
+var errBinaryMatches = errors.New("binary file matches")
+
+// defaultParallel возвращает число потоков поиска по умолчанию.
+func defaultParallel() int {
+	return min(runtime.NumCPU(), maxDefaultParallel)
+}
+
+// validateParallel проверяет значение --parallel.
+func validateParallel(n int) error {
+	if n < 1 {
+		return fmt.Errorf("invalid number of threads %d: must be at least 1", n)
+	}
+	return nil
+}
+
+// fileFilter отбирает файлы и каталоги по шаблонам --include, --exclude и --exclude-dir.
+// Шаблоны сравниваются с именем файла без каталога.
+type fileFilter struct {
+	include    []string
+	exclude    []string
+	excludeDir []string
+}
+
+// validate проверяет синтаксис шаблонов.
+func (f fileFilter) validate() error {
+	for _, globs := range [][]string{f.include, f.exclude, f.excludeDir} {
+		for _, glob := range globs {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return fmt.Errorf("invalid glob %q: %w", glob, err)
+			}
+		}
+	}
+	return nil
+}
+
+// file сообщает, нужно ли искать в файле path.
+func (f fileFilter) file(path string) bool {
+	name := filepath.Base(path)
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	return !matchAny(f.exclude, name)
+}
+
+// dir сообщает, нужно ли обходить каталог path.
+func (f fileFilter) dir(path string) bool {
+	return !matchAny(f.excludeDir, filepath.Base(path))
+}
+
+// matchAny сообщает, что имя подходит хотя бы под один шаблон.
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// searchConfig — параметры поиска по нескольким входам.
+type searchConfig struct {
+	match     matcher
+	opts      options
+	recursive bool       // Обходить каталоги (-r)
+	filter    fileFilter // Шаблоны --include, --exclude и --exclude-dir
+	ignore    bool       // Учитывать файлы .gitignore при обходе каталогов
+	parallel  int        // Число файлов, в которых ищется одновременно
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+}
+
+// searchJob — поиск в одном файле; вывод идёт в out.
+// Ошибка обхода тоже становится заданием, чтобы сообщение о ней вышло на своём месте.
+type searchJob struct {
+	path     string
+	named    bool // Файл указан в командной строке, а не найден обходом каталога
+	out      jobOutput
+	selected int
+	err      error
+	done     chan struct{}
+}
+
+// jobOutput — вывод задания. Пока впереди в очереди есть незавершённые файлы,
+// вывод копится в памяти; задание в голове очереди пишет прямо в stdout, так что
+// поиск в нём, например в stdin, остаётся потоковым.
+type jobOutput struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	w         io.Writer // Куда писать напрямую; nil — пока вывод копится
+	separator string    // Разделитель групп перед первым выводом файла; пустой — без разделителя
+	wrote     bool      // Файл уже что-то вывел
+}
+
+// Write копит p или, если файл уже в голове очереди, выводит сразу.
+func (o *jobOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if o.w == nil {
+		return o.buf.Write(p)
+	}
+	if err := o.writeSeparator(); err != nil {
+		return 0, err
+	}
+	return o.w.Write(p)
+}
+
+// writeSeparator выводит разделитель групп перед первыми строками файла.
+func (o *jobOutput) writeSeparator() error {
+	if o.wrote {
+		return nil
+	}
+	o.wrote = true
+	_, err := io.WriteString(o.w, o.separator)
+	return err
+}
+
+// stream выводит накопленное в w и направляет туда дальнейший вывод.
+// separator выводится перед первыми строками файла, если они есть.
+func (o *jobOutput) stream(w io.Writer, separator string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w, o.separator = w, separator
+	if o.buf.Len() == 0 {
+		return nil
+	}
+	if err := o.writeSeparator(); err != nil {
+		return err
+	}
+	_, err := o.buf.WriteTo(w)
+	o.buf = bytes.Buffer{}
+	return err
+}
+
+// search ищет во входах по порядку и возвращает число выбранных строк и признак ошибки.
+// Единственный вход, кроме обходимого с -r каталога, читается потоково прямо в stdout
+// без имени файла, как в grep; иначе файлы ищутся пулом
+// из cfg.parallel потоков с именем файла перед каждой строкой. Файл в голове очереди
+// выводится сразу, а вывод файлов, найденных раньше своей очереди, копится и печатается
+// целиком, так что вывод файлов не перемешивается и идёт в порядке обхода.
+func search(inputs []string, cfg searchConfig) (int, bool) {
+	if len(inputs) == 1 && !(cfg.recursive && isDir(inputs[0])) {
+		return searchOne(inputs[0], cfg)
+	}
+
+	cfg.opts.withFilename = true
+	jobs := make(chan *searchJob)
+	ordered := make(chan *searchJob, 2*cfg.parallel) // Ограничивает число готовых, но не выведенных файлов
+
+	// Обход входов: задания уходят в пул и в очередь вывода в одном порядке.
+	go func() {
+		defer close(jobs)
+		defer close(ordered)
+		emit := func(path string, named bool, err error) {
+			job := &searchJob{path: path, named: named, err: err, done: make(chan struct{})}
+			ordered <- job
+			if err != nil {
+				close(job.done)
+				return
+			}
+			jobs <- job
+		}
+		for _, input := range inputs {
+			cfg.walk(input, emit)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.selected, job.err = searchFile(job.path, job.named, &job.out, cfg)
+				close(job.done)
+			}
+		}()
+	}
+
+	selected, printed, failed := 0, false, false
+	for job := range ordered {
+		// Группы строк разных файлов тоже разделяются, если задан контекст.
+		separator := ""
+		if printed && cfg.opts.separated() {
+			separator = cfg.opts.separatorLine()
+		}
+		err := job.out.stream(cfg.stdout, separator)
+		<-job.done
+		selected += job.selected
+		printed = printed || job.out.wrote
+		if job.err == nil {
+			job.err = err
+		}
+		if job.err != nil {
+			fmt.Fprintln(cfg.stderr, "grep:", job.err)
+			failed = failed || !errors.Is(job.err, errBinaryMatches)
+		}
+	}
+	wg.Wait()
+	return selected, failed
+}
+
+// searchOne ищет в единственном входе, выводя строки сразу.
+func searchOne(input string, cfg searchConfig) (int, bool) {
+	selected, failed := 0, false
+	cfg.walk(input, func(path string, named bool, err error) {
+		if err == nil {
+			selected, err = searchFile(path, named, cfg.stdout, cfg)
+		}
+		if err != nil {
+			fmt.Fprintln(cfg.stderr, "grep:", err)
+			failed = failed || !errors.Is(err, errBinaryMatches)
+		}
+	})
+	return selected, failed
+}
+
+// walk передаёт emit файлы входа или ошибки: сам файл или, с -r, файлы каталога
+// в лексикографическом порядке. "-" означает stdin, пустая строка — текущий каталог.
+// named отличает входы из командной строки от файлов, найденных обходом.
+func (cfg searchConfig) walk(input string, emit func(path string, named bool, err error)) {
+	if input == "-" {
+		emit(input, true, nil)
+		return
+	}
+	if input == "" {
+		cfg.walkDir(input, nil, emit)
+		return
+	}
+	info, err := os.Stat(input)
+	switch {
+	case err != nil:
+		emit(input, true, pathError(err))
+	case !info.IsDir():
+		if cfg.filter.file(input) {
+			emit(input, true, nil)
+		}
+	case !cfg.recursive:
+		emit(input, true, fmt.Errorf("%s: Is a directory", input))
+	default:
+		cfg.walkDir(input, nil, emit)
+	}
+}
+
+// walkDir обходит каталог dir с правилами игнорирования rules родительских каталогов.
+func (cfg searchConfig) walkDir(dir string, rules *ignoreRules, emit func(path string, named bool, err error)) {
+	name := dir
+	if name == "" {
+		name = "."
+	}
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		emit(name, false, pathError(err))
+		return
+	}
+	if cfg.ignore {
+		if rules, err = rules.load(dir); err != nil {
+			emit(name, false, pathError(err))
+		}
+	}
+
+	for _, entry := range entries {
+		path := joinPath(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			if cfg.filter.dir(path) && !(cfg.ignore && (entry.Name() == ".git" || rules.ignored(path, true))) {
+				cfg.walkDir(path, rules, emit)
+			}
+		case entry.Type().IsRegular(): // Символические ссылки при обходе, как и в grep -r, не разыменовываются
+			if cfg.filter.file(path) && !(cfg.ignore && rules.ignored(path, false)) {
+				emit(path, false, nil)
+			}
+		}
+	}
+}
+
+// searchFile ищет в файле path. Двоичные файлы, найденные обходом каталога, пропускаются;
+// в двоичном файле из командной строки вместо строк сообщается только, что они есть.
+func searchFile(path string, named bool, w io.Writer, cfg searchConfig) (int, error) {
+	opts := cfg.opts
+	opts.filename = displayName(path)
+	if path == "-" {
+		n, err := grep(cfg.stdin, w, cfg.match, opts)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", opts.filename, err)
+		}
+		return n, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, pathError(err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReaderSize(file, 64<<10)
+	notice := false // Вместо строк сообщить, что в двоичном файле есть выбранные
+	if info, err := file.Stat(); err == nil && info.Mode().IsRegular() && isBinary(r) {
+		if !named {
+			return 0, nil
+		}
+		if !opts.summary() {
+			// Строки двоичного файла не выводятся: достаточно найти первую выбранную.
+			opts = options{list: listMatching, invert: opts.invert, maxLine: opts.maxLine}
+			w, notice = io.Discard, true
+		}
+	}
+	n, err := grep(r, w, cfg.match, opts)
+	if err == nil && notice && n > 0 {
+		err = errBinaryMatches
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	return n, err
+}
+
+// isDir сообщает, что вход — каталог; пустая строка означает текущий каталог.
+func isDir(input string) bool {
+	if input == "" {
+		return true
+	}
+	info, err := os.Stat(input)
+	return err == nil && info.IsDir()
+}
+
+// isBinary сообщает, что в начале потока есть нулевой байт — признак двоичного файла, как в grep.
+func isBinary(r *bufio.Reader) bool {
+	head, _ := r.Peek(binaryPeekSize)
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// pathError убирает из ошибки файловой системы имя операции: "name: reason", как в grep.
+func pathError(err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return fmt.Errorf("%s: %w", pe.Path, pe.Err)
+	}
+	return err
+}
+
+// displayName возвращает имя входа для вывода и сообщений об ошибках.
+func displayName(path string) string {
+	if path == "" || path == "-" {
+		return "(standard input)"
+	}
+	return path
+}
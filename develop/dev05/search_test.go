@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	root := &ignoreRules{base: "", rules: parseRules(t, "build/", "*.log", "!keep.log", "/top.txt", "docs/**/*.tmp", "a?c", "[!x]y")}
+	nested := &ignoreRules{parent: root, base: "src", rules: parseRules(t, "*.pb.go", "/local", "gen/")}
+
+	tests := []struct {
+		rules *ignoreRules
+		path  string
+		dir   bool
+		want  bool
+	}{
+		{root, "build", true, true},
+		{root, "build", false, false}, // Правило "build/" действует только на каталоги
+		{root, "src/build", true, true},
+		{root, "x.log", false, true},
+		{root, "logs/deep/x.log", false, true},
+		{root, "logs/keep.log", false, false}, // Отрицание возвращает файл
+		{root, "top.txt", false, true},
+		{root, "src/top.txt", false, false}, // "/top.txt" привязан к каталогу файла правил
+		{root, "docs/x.tmp", false, true},
+		{root, "docs/a/b/x.tmp", false, true},
+		{root, "other/docs/x.tmp", false, false},
+		{root, "abc", false, true},
+		{root, "abbc", false, false},
+		{root, "ay", false, true},
+		{root, "xy", false, false},
+		{nested, "src/x.pb.go", false, true},
+		{nested, "src/local", false, true},
+		{nested, "src/a/local", false, false},
+		{nested, "src/gen", true, true},
+		{nested, "src/x.log", false, true}, // Правила родителя действуют и во вложенном каталоге
+		{nested, "src/keep.log", false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.rules.ignored(tt.path, tt.dir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func parseRules(t *testing.T, lines ...string) []ignoreRule {
+	t.Helper()
+	var rules []ignoreRule
+	for _, line := range lines {
+		rule, ok := parseIgnoreRule(line)
+		if !ok {
+			t.Fatalf("parseIgnoreRule(%q): rule skipped", line)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func TestParseIgnoreRuleSkips(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		if _, ok := parseIgnoreRule(line); ok {
+			t.Errorf("parseIgnoreRule(%q): want skipped", line)
+		}
+	}
+	if rule, ok := parseIgnoreRule(`\#hash`); !ok || !rule.re.MatchString("#hash") {
+		t.Errorf(`parseIgnoreRule("\#hash") should match "#hash"`)
+	}
+}
+
+// writeTree создаёт файлы в каталоге dir; ключ — путь через "/".
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runSearch ищет в inputs относительно каталога dir.
+func runSearch(t *testing.T, dir string, inputs []string, cfg searchConfig) (string, string, int, bool) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var stdout, stderr bytes.Buffer
+	cfg.stdout, cfg.stderr = &stdout, &stderr
+	if cfg.match == nil {
+		cfg.match = regexp.MustCompile("needle")
+	}
+	if cfg.parallel == 0 {
+		cfg.parallel = 4
+	}
+	selected, failed := search(inputs, cfg)
+	return stdout.String(), stderr.String(), selected, failed
+}
+
+func TestSearchRecursive(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":      "build/\n*.log\n!keep.log\n",
+		".git/config":     "needle\n",
+		"a.txt":           "needle a\nhay\n",
+		"b.go":            "hay\nneedle b\n",
+		"bin.dat":         "needle\x00binary\n",
+		"build/out.txt":   "needle\n",
+		"logs/x.log":      "needle\n",
+		"logs/keep.log":   "needle keep\n",
+		"src/.gitignore":  "*.pb.go\n",
+		"src/main.go":     "needle main\n",
+		"src/api.pb.go":   "needle\n",
+		"src/vendor/v.go": "needle vendor\n",
+	})
+
+	tests := []struct {
+		name   string
+		inputs []string
+		cfg    searchConfig
+		want   string
+	}{
+		{
+			name:   "текущий каталог с .gitignore",
+			inputs: []string{""},
+			cfg:    searchConfig{recursive: true, ignore: true},
+			want:   "a.txt:needle a\nb.go:needle b\nlogs/keep.log:needle keep\nsrc/main.go:needle main\nsrc/vendor/v.go:needle vendor\n",
+		},
+		{
+			name:   "без .gitignore",
+			inputs: []string{"src"},
+			cfg:    searchConfig{recursive: true},
+			want:   "src/api.pb.go:needle\nsrc/main.go:needle main\nsrc/vendor/v.go:needle vendor\n",
+		},
+		{
+			name:   "include и exclude-dir",
+			inputs: []string{"."},
+			cfg:    searchConfig{recursive: true, ignore: true, filter: fileFilter{include: []string{"*.go"}, excludeDir: []string{"vendor"}}},
+			want:   "./b.go:needle b\n./src/main.go:needle main\n",
+		},
+		{
+			name:   "exclude",
+			inputs: []string{"src/"},
+			cfg:    searchConfig{recursive: true, filter: fileFilter{exclude: []string{"*.pb.go", "v*"}}},
+			want:   "src/main.go:needle main\n",
+		},
+		{
+			name:   "подсчёт по файлам",
+			inputs: []string{"a.txt", "b.go", "bin.dat"},
+			cfg:    searchConfig{opts: options{count: true}},
+			want:   "a.txt:1\nb.go:1\nbin.dat:1\n",
+		},
+		{
+			name:   "контекст разделяет файлы",
+			inputs: []string{"a.txt", "b.go"},
+			cfg:    searchConfig{opts: options{before: 1, number: true}},
+			want:   "a.txt:1:needle a\n--\nb.go-1-hay\nb.go:2:needle b\n",
+		},
+		{
+			name:   "имена файлов с совпадениями",
+			inputs: []string{"src"},
+			cfg:    searchConfig{recursive: true, opts: options{list: listMatching}},
+			want:   "src/api.pb.go\nsrc/main.go\nsrc/vendor/v.go\n",
+		},
+		{
+			name:   "имя единственного файла без совпадений",
+			inputs: []string{"src/.gitignore"},
+			cfg:    searchConfig{opts: options{list: listNonMatching}},
+			want:   "src/.gitignore\n",
+		},
+		{
+			name:   "один файл с -r без имени",
+			inputs: []string{"a.txt"},
+			cfg:    searchConfig{recursive: true},
+			want:   "needle a\n",
+		},
+		{
+			name:   "один каталог с -r с именами",
+			inputs: []string{"logs"},
+			cfg:    searchConfig{recursive: true},
+			want:   "logs/keep.log:needle keep\nlogs/x.log:needle\n",
+		},
+		{
+			name:   "один файл без имени",
+			inputs: []string{"b.go"},
+			cfg:    searchConfig{opts: options{number: true}},
+			want:   "2:needle b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stderr, _, failed := runSearch(t, dir, tt.inputs, tt.cfg)
+			if got != tt.want || failed {
+				t.Errorf("search(%q) = %q, failed=%v (%s); want %q", tt.inputs, got, failed, stderr, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "needle\n", "sub/b.txt": "needle\n"})
+
+	got, stderr, selected, failed := runSearch(t, dir, []string{"missing", "a.txt", "sub"}, searchConfig{})
+	if got != "a.txt:needle\n" || selected != 1 || !failed {
+		t.Errorf("search() = %q, %d, failed=%v; want %q, 1, true", got, selected, failed, "a.txt:needle\n")
+	}
+	// Ошибки выводятся в порядке входов, после вывода предыдущих файлов.
+	if want := "grep: missing: no such file or directory\ngrep: sub: Is a directory\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+
+	if _, stderr, _, failed := runSearch(t, dir, []string{"sub"}, searchConfig{}); !failed || stderr != "grep: sub: Is a directory\n" {
+		t.Errorf("search(dir) without -r: stderr = %q, failed=%v", stderr, failed)
+	}
+}
+
+func TestSearchBinary(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt":       "needle a\n",
+		"bin.dat":     "needle\x00binary\nneedle 2\n",
+		"hay.dat":     "hay\x00binary\n",
+		"sub/bin.dat": "needle\x00\n",
+	})
+
+	tests := []struct {
+		name     string
+		inputs   []string
+		cfg      searchConfig
+		want     string
+		stderr   string
+		selected int
+	}{
+		{
+			name:     "двоичный файл из командной строки",
+			inputs:   []string{"bin.dat"},
+			stderr:   "grep: bin.dat: binary file matches\n",
+			selected: 1,
+		},
+		{
+			name:     "двоичный файл среди других",
+			inputs:   []string{"bin.dat", "a.txt", "hay.dat"},
+			cfg:      searchConfig{opts: options{number: true}},
+			want:     "a.txt:1:needle a\n",
+			stderr:   "grep: bin.dat: binary file matches\n",
+			selected: 2,
+		},
+		{
+			name:     "подсчёт в двоичном файле",
+			inputs:   []string{"bin.dat", "hay.dat"},
+			cfg:      searchConfig{opts: options{count: true}},
+			want:     "bin.dat:2\nhay.dat:0\n",
+			selected: 2,
+		},
+		{
+			name:     "списки файлов",
+			inputs:   []string{"bin.dat", "hay.dat"},
+			cfg:      searchConfig{opts: options{list: listNonMatching}},
+			want:     "hay.dat\n",
+			selected: 1,
+		},
+		{
+			name:     "при обходе каталога двоичные файлы пропускаются",
+			inputs:   []string{"sub", "a.txt"},
+			cfg:      searchConfig{recursive: true},
+			want:     "a.txt:needle a\n",
+			selected: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stderr, selected, failed := runSearch(t, dir, tt.inputs, tt.cfg)
+			if got != tt.want || stderr != tt.stderr || selected != tt.selected || failed {
+				t.Errorf("search(%q) = %q, stderr %q, %d, failed=%v; want %q, stderr %q, %d",
+					tt.inputs, got, stderr, selected, failed, tt.want, tt.stderr, tt.selected)
+			}
+		})
+	}
+}
+
+// TestSearchOrder проверяет, что вывод файлов не перемешивается и идёт в порядке обхода
+// при любом числе потоков.
+func TestSearchOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := make(map[string]string)
+	var want strings.Builder
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("d%d/f%03d.txt", i%3, i)
+		var content strings.Builder
+		for j := 0; j < i%50+1; j++ {
+			fmt.Fprintf(&content, "needle %d %d\nhay\n", i, j)
+		}
+		files[name] = content.String()
+	}
+	writeTree(t, dir, files)
+	for d := 0; d < 3; d++ {
+		for i := d; i < 200; i += 3 {
+			for j := 0; j < i%50+1; j++ {
+				fmt.Fprintf(&want, "d%d/f%03d.txt:needle %d %d\n", d, i, i, j)
+			}
+		}
+	}
+
+	for _, parallel := range []int{1, 3, 16} {
+		got, stderr, _, failed := runSearch(t, dir, []string{""}, searchConfig{recursive: true, parallel: parallel})
+		if got != want.String() || failed {
+			t.Errorf("parallel=%d: output differs from sequential order (failed=%v, %s)", parallel, failed, stderr)
+		}
+	}
+}
+
+// TestSearchStreaming проверяет, что среди нескольких входов строки медленного stdin
+// выводятся сразу, не дожидаясь конца ввода, а файлы после него — в своём порядке.
+func TestSearchStreaming(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "needle a\n"})
+	file := filepath.Join(dir, "a.txt")
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan bool, 1)
+	go func() {
+		_, failed := search([]string{"-", file}, searchConfig{
+			match:    regexp.MustCompile("needle"),
+			opts:     options{after: 1},
+			parallel: 4,
+			stdin:    inR,
+			stdout:   outW,
+			stderr:   io.Discard,
+		})
+		outW.Close()
+		done <- failed
+	}()
+
+	lines := bufio.NewReader(outR)
+	readLine := func() string {
+		result := make(chan string, 1)
+		go func() {
+			line, _ := lines.ReadString('\n')
+			result <- line
+		}()
+		select {
+		case line := <-result:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("search did not write stdin matches while waiting for input")
+			return ""
+		}
+	}
+
+	io.WriteString(inW, "needle 1\n")
+	if got, want := readLine(), "(standard input):needle 1\n"; got != want {
+		t.Errorf("output line = %q, want %q", got, want)
+	}
+	io.WriteString(inW, "hay\n")
+	if got, want := readLine(), "(standard input)-hay\n"; got != want {
+		t.Errorf("output line = %q, want %q", got, want)
+	}
+
+	inW.Close()
+	rest, _ := io.ReadAll(lines)
+	if want := "--\n" + file + ":needle a\n"; string(rest) != want {
+		t.Errorf("output after stdin = %q, want %q", rest, want)
+	}
+	if <-done {
+		t.Error("search() failed")
+	}
+}
+
+func TestFileFilter(t *testing.T) {
+	if err := (fileFilter{include: []string{"["}}).validate(); err == nil {
+		t.Error("validate() with invalid glob: want error")
+	}
+	if err := validateParallel(0); err == nil {
+		t.Error("validateParallel(0): want error")
+	}
+
+	f := fileFilter{include: []string{"*.go", "*.md"}, exclude: []string{"*_test.go"}, excludeDir: []string{".*"}}
+	for path, want := range map[string]bool{"a.go": true, "dir/b.md": true, "a_test.go": false, "c.txt": false} {
+		if got := f.file(path); got != want {
+			t.Errorf("file(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if f.dir("src/.cache") || !f.dir("src") {
+		t.Error("dir() should skip only directories matching --exclude-dir")
+	}
+}
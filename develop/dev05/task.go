@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"regexp"
@@ -27,22 +26,6 @@ import (
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
-// openFile открывает файл и считывает его содержимое в слайс строк.
-func openFile(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	data := make([]string, 0)
-	for scanner.Scan() {
-		data = append(data, scanner.Text())
-	}
-	return data, nil
-}
-
 // getExpression компилирует регулярное выражение, добавляя опцию игнорирования регистра при необходимости.
 func getExpression(pattern string, ignore bool) (*regexp.Regexp, error) {
 	ignorePrefix := ""
@@ -56,93 +39,172 @@ func getExpression(pattern string, ignore bool) (*regexp.Regexp, error) {
 	return compiledExpession, nil
 }
 
-// getNumberOfIntersections считает количество строк, совпадающих с заданным регулярным выражением.
-func getNumberOfIntersections(file []string, expression *regexp.Regexp) int {
-	result := 0
-	for _, str := range file {
-		match := expression.Match([]byte(str))
-		if match {
-			result++
+func main() {
+	// Определение и парсинг флагов командной строки.
+	var patterns patternsValue
+	var patternFiles filesValue
+	var includes, excludes, excludeDirs filesValue
+	getopt.SetParameters("[pattern] [file ...]")
+	getopt.Var(&patterns, 'e', "паттерн; можно указать несколько раз")
+	getopt.Var(&patternFiles, 'f', "файл с паттернами, по одному в строке; можно указать несколько раз")
+	fixed := getopt.Bool('F', "искать фиксированные строки, а не регулярные выражения")
+	after := getopt.IntLong("after", 'A', 0, "вывод N строк после совпадения")
+	before := getopt.IntLong("before", 'B', 0, "вывод N строк до совпадения")
+	inTheMiddle := getopt.IntLong("context", 'C', 0, "вывод N строк до и после совпадения")
+	count := getopt.Bool('c', "вывести количество строк с совпадением")
+	ignore := getopt.Bool('i', "игнорировать различия регистра")
+	invert := getopt.Bool('v', "инвертировать вывод")
+	number := getopt.Bool('n', "напечатать номер строки")
+	onlyMatching := getopt.BoolLong("only-matching", 'o', "выводить только совпавшие части строк")
+	var list listMode
+	getopt.VarLong(&listFlag{&list, listMatching}, "files-with-matches", 'l', "выводить только имена файлов с совпадениями").SetFlag()
+	getopt.VarLong(&listFlag{&list, listNonMatching}, "files-without-match", 'L', "выводить только имена файлов без совпадений").SetFlag()
+	maxCount := getopt.IntLong("max-count", 'm', -1, "остановиться после N выбранных строк", "N")
+	word := getopt.BoolLong("word-regexp", 'w', "искать совпадения только целыми словами")
+	wholeLine := getopt.BoolLong("line-regexp", 'x', "искать совпадения только со всей строкой")
+	color := colorValue("never")
+	getopt.VarLong(&color, "color", 0, "выделять совпадения цветом: never, always или auto", "WHEN").SetOptional()
+	jsonOutput := getopt.BoolLong("json", 0, "выводить совпадения объектами JSON, по одному в строке")
+	recursive := getopt.BoolLong("recursive", 'r', "искать во всех файлах каталогов рекурсивно")
+	getopt.VarLong(&includes, "include", 0, "искать только в файлах, имя которых подходит под шаблон", "GLOB")
+	getopt.VarLong(&excludes, "exclude", 0, "пропускать файлы, имя которых подходит под шаблон", "GLOB")
+	getopt.VarLong(&excludeDirs, "exclude-dir", 0, "не обходить каталоги, имя которых подходит под шаблон", "GLOB")
+	noIgnore := getopt.BoolLong("no-ignore", 0, "не учитывать файлы .gitignore при обходе каталогов")
+	parallel := getopt.IntLong("parallel", 0, defaultParallel(), "число файлов, в которых ищется одновременно", "N")
+
+	getopt.Parse()
+
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, "grep:", err)
+		os.Exit(2)
+	}
+
+	if *after < 0 || *before < 0 || *inTheMiddle < 0 {
+		fail(fmt.Errorf("invalid context length"))
+	}
+
+	// Без -e и -f паттерн — первый аргумент, за ним необязательный файл; без файла
+	// или с именем "-" читается stdin.
+	args := getopt.Args()
+	if !getopt.IsSet('e') && !getopt.IsSet('f') {
+		if len(args) == 0 {
+			getopt.Usage()
+			os.Exit(2)
 		}
+		patterns.Set(args[0], nil)
+		args = args[1:]
+	}
+	filter := fileFilter{include: includes, exclude: excludes, excludeDir: excludeDirs}
+	if err := filter.validate(); err != nil {
+		fail(err)
+	}
+	if err := validateParallel(*parallel); err != nil {
+		fail(err)
 	}
-	return result
-}
 
-// reg обрабатывает строки файла, печатая строки в соответствии с заданными параметрами.
-func reg(file []string, expression *regexp.Regexp, after, before int, number, invert bool) {
-	for i, str := range file {
-		match := expression.Match([]byte(str))
-		if invert && !match {
-			echo(file, i, after, before, number)
-		} else if !invert && match {
-			echo(file, i, after, before, number)
+	for _, name := range patternFiles {
+		filePatterns, err := readPatterns(name)
+		if err != nil {
+			fail(err)
 		}
+		patterns = append(patterns, filePatterns...)
 	}
-}
 
-// echo выводит строки файла с учетом контекста (до и после совпадения) и номера строки.
-func echo(file []string, i, after, before int, number bool) {
-	startPoint := 0
-	endPoint := len(file)
-	if i-after > 0 {
-		startPoint = i - after
+	// Компиляция паттернов.
+	match, err := newMatcher(patterns, matchOptions{fixed: *fixed, ignoreCase: *ignore, word: *word, line: *wholeLine})
+	if err != nil {
+		fail(err)
 	}
-	if i+before < len(file) {
-		endPoint = i + before
+
+	// -C задаёт контекст с обеих сторон; явные -A и -B имеют приоритет.
+	if !getopt.IsSet('A') {
+		*after = *inTheMiddle
 	}
-	if endPoint != len(file) {
-		endPoint++
+	if !getopt.IsSet('B') {
+		*before = *inTheMiddle
 	}
-	fmt.Println("------------------------")
-	for line := startPoint; line < endPoint; line++ {
-		if number {
-			fmt.Printf("%d: ", line+1)
+
+	// Без файлов читается stdin, а с -r — текущий каталог; имена выводятся без "./".
+	if len(args) == 0 {
+		args = []string{"-"}
+		if *recursive {
+			args = []string{""}
 		}
-		fmt.Printf("%s\n", file[line])
 	}
-	fmt.Println("------------------------")
+
+	if *maxCount == 0 {
+		os.Exit(1) // Как и grep, с -m 0 входы не читаются
+	}
+
+	// Выполнение поиска с учетом параметров; файлы читаются построчно, а не целиком.
+	opts := options{after: *after, before: *before, count: *count, invert: *invert, number: *number}
+	opts.context = getopt.IsSet('A') || getopt.IsSet('B') || getopt.IsSet('C')
+	opts.maxCount = max(*maxCount, 0)
+	opts.onlyMatching, opts.json = *onlyMatching, *jsonOutput
+	opts.color = !*jsonOutput && color.enabled()
+	opts.list = list
+	selected, failed := search(args, searchConfig{
+		match:     match,
+		opts:      opts,
+		recursive: *recursive,
+		filter:    filter,
+		ignore:    !*noIgnore,
+		parallel:  *parallel,
+		stdin:     os.Stdin,
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+	})
+	// Как и grep: код 2 при ошибке, 1 — если ни одна строка не выбрана.
+	switch {
+	case failed:
+		os.Exit(2)
+	case selected == 0:
+		os.Exit(1)
+	}
 }
 
-func main() {
-	// Определение и парсинг флагов командной строки.
-	pattern := getopt.String('e', "", "паттерн")
-	path := getopt.String('f', "", "файл")
-	after := getopt.IntLong("after", 'A', 0, "вывод N строк после совпадения")
-	before := getopt.IntLong("before", 'B', 0, "вывод N строк до совпадения")
-	inTheMiddle := getopt.IntLong("context", 'C', 0, "вывод N строк в районе совпадения")
-	count := getopt.Bool('c', "вывести количество строк с совпадением")
-	ignore := getopt.Bool('i', "игнорировать различия регистра")
-	invert := getopt.Bool('v', "инвертировать вывод")
-	number := getopt.Bool('n', "напечатать номер строки")
+// listFlag — флаг -l или -L; как и в grep, действует последний из них.
+type listFlag struct {
+	mode  *listMode
+	value listMode
+}
 
-	getopt.Parse()
+func (f *listFlag) Set(string, getopt.Option) error {
+	*f.mode = f.value
+	return nil
+}
 
-	// Чтение файла.
-	file, err := openFile(*path)
-	if err != nil {
-		panic(err)
-	}
+func (f *listFlag) String() string {
+	return ""
+}
 
-	// Компиляция регулярного выражения.
-	expression, err := getExpression(*pattern, *ignore)
-	if err != nil {
-		panic(err)
+// colorValue — значение --color. Флаг без значения равносилен --color=auto.
+type colorValue string
+
+func (c *colorValue) Set(value string, _ getopt.Option) error {
+	switch value {
+	case "":
+		*c = "auto"
+	case "never", "always", "auto":
+		*c = colorValue(value)
+	default:
+		return fmt.Errorf("invalid --color value %q: want never, always or auto", value)
 	}
+	return nil
+}
 
-	// Обработка флага подсчета совпадений.
-	if *count {
-		result := getNumberOfIntersections(file, expression)
-		if *invert {
-			result = len(file) - result
-		}
-		fmt.Println(result)
-	} else {
-		// Обработка флага контекста.
-		if *after == 0 && *before == 0 && *inTheMiddle != 0 {
-			*after = *inTheMiddle / 2
-			*before = *inTheMiddle / 2
-		}
-		// Выполнение поиска с учетом параметров.
-		reg(file, expression, *after, *before, *number, *invert)
+func (c *colorValue) String() string {
+	return string(*c)
+}
+
+// enabled сообщает, выделять ли вывод цветом; в режиме auto — если вывод идёт в терминал.
+func (c colorValue) enabled() bool {
+	switch c {
+	case "always":
+		return true
+	case "auto":
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
 	}
+	return false
 }